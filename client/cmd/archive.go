@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"boyl/pkg/archive"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// NewArchiveCommand returns the command for inspecting downloaded archives without installing them.
+func NewArchiveCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "archive",
+		Short: "Inspect game archives",
+	}
+
+	command.AddCommand(&cobra.Command{
+		Use:          "list <file>",
+		Short:        "Lists all entries of an archive",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			extractor, file, err := openArchive(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			entries, err := extractor.List()
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				fmt.Printf("%s %12d %08x %s\n", entry.Mode, entry.Size, entry.CRC32, entry.Name)
+			}
+			return nil
+		},
+	})
+
+	command.AddCommand(&cobra.Command{
+		Use:          "test <file>...",
+		Short:        "Decompresses archives and verifies their checksums without extracting them",
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			var failed int
+			for _, path := range args {
+				if err := testArchive(command.Context(), path); err != nil {
+					fmt.Printf("FAIL %s: %v\n", path, err)
+					failed++
+					continue
+				}
+				fmt.Printf("OK   %s\n", path)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d archives are corrupt", failed, len(args))
+			}
+			return nil
+		},
+	})
+
+	return command
+}
+
+func openArchive(path string) (archive.Extractor, *os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	extractor, err := archive.NewExtractor(filepath.Base(path), file, info.Size(), archive.DefaultLimits)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return extractor, file, nil
+}
+
+func testArchive(ctx context.Context, path string) error {
+	extractor, file, err := openArchive(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extractor.Test(ctx)
+}
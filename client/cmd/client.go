@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"boyl/client/pkg/remote"
+	"boyl/client/pkg/servers"
+	"boyl/client/pkg/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// errNotRunning is returned when there is no client serving the UI to hand a command over to.
+var errNotRunning = errors.New("client is not running")
+
+// callClient sends a request to the API of the client serving the UI, with body encoded as JSON unless it is nil. Commands
+// changing state go through it when it is running, so the UI stays up to date and there is only one process downloading or playing games.
+func callClient(method, path string, query url.Values, body any) error {
+	var reader io.Reader
+	if body != nil {
+		marshaled, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(marshaled)
+	}
+	req, err := http.NewRequest(method, "http://"+services.Address+path+"?"+query.Encode(), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return errNotRunning
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return apiError(res)
+	}
+	return nil
+}
+
+// apiError returns the message of an error response of the client.
+func apiError(res *http.Response) error {
+	body, _ := io.ReadAll(res.Body)
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Message != "" {
+		return errors.New(apiErr.Message)
+	}
+	return fmt.Errorf("unexpected status code: %d, body: %s", res.StatusCode, body)
+}
+
+// openServices prepares the database and the services for a command running without the UI.
+func openServices(app core.App) (*services.Services, error) {
+	if err := app.RunAllMigrations(); err != nil {
+		return nil, err
+	}
+	return services.New(app)
+}
+
+// openRemote is openServices for commands that need a server, the default one if server is empty.
+// It fails if the client isn't logged in to the server.
+func openRemote(ctx context.Context, app core.App, server string) (*services.Services, *remote.Client, error) {
+	svc, err := openServices(app)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := svc.Servers.Client(server)
+	if errors.Is(err, servers.ErrNotFound) && server == "" {
+		return nil, nil, errors.New("not logged in, see the login command")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// failing to log in to one of the other servers doesn't matter here
+	authErr := svc.Authenticate(ctx)
+	if r.Identity() == "" {
+		if authErr != nil {
+			return nil, nil, fmt.Errorf("failed to log in: %w", authErr)
+		}
+		return nil, nil, errors.New("not logged in, see the login command")
+	}
+	return svc, r, nil
+}
+
+var failed atomic.Bool
+
+// TrackFailures wraps the commands of the tree, so Failed reports whether one of them returned an error.
+// The PocketBase CLI prints the error of a command, but exits successfully anyway, which breaks scripts.
+func TrackFailures(command *cobra.Command) {
+	if run := command.RunE; run != nil {
+		command.RunE = func(command *cobra.Command, args []string) error {
+			err := run(command, args)
+			if err != nil {
+				failed.Store(true)
+			}
+			return err
+		}
+	}
+	for _, child := range command.Commands() {
+		TrackFailures(child)
+	}
+}
+
+// Failed reports whether a command wrapped by TrackFailures failed.
+func Failed() bool {
+	return failed.Load()
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// NewStatusCommand returns the command listing the downloads and their progress.
+func NewStatusCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "status",
+		Short:        "Lists the downloads and their progress",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+
+			downloads, err := app.FindRecordsByFilter(svc.DownloadsCollection, "", "-created", 0, 0)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tGAME\tLIBRARY\tSTATUS")
+			for _, download := range downloads {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", download.Id, download.GetString("game"), download.GetString("library"), formatDownload(download))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// NewCancelCommand returns the command cancelling a download, or removing it from the list once it finished.
+func NewCancelCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "cancel <download id>",
+		Short:        "Cancels a download or removes a finished one",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := callClient(http.MethodDelete, "/api/download", url.Values{"id": {args[0]}}, nil)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			return svc.Downloads.Cancel(args[0])
+		},
+	}
+}
+
+// formatDownload describes the state of a download, with the progress and speed while it is running.
+func formatDownload(download *core.Record) string {
+	status := download.GetString("status")
+	switch status {
+	case "downloading", "extracting", "moving":
+		return fmt.Sprintf("%s %5.1f%% %s/s", status, download.GetFloat("progress")*100, formatBytes(download.GetFloat("speed")))
+	case "failed":
+		if text := download.GetString("text"); text != "" {
+			return status + ": " + text
+		}
+	}
+	return status
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"boyl/client/pkg/remote"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// NewListCommand returns the command listing the installed games.
+func NewListCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "list",
+		Short:        "Lists the installed games",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			// names come from the server, offline the directory has to do
+			if err := svc.Authenticate(command.Context()); err != nil {
+				app.Logger().Warn("failed to log in", "error", err)
+			}
+
+			games, err := app.FindAllRecords(svc.GamesCollection)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tSERVER\tPATH")
+			for _, game := range games {
+				name := filepath.Base(game.GetString("path"))
+				var serverName string
+				if server, err := svc.Servers.Find(game.GetString("server")); err == nil {
+					serverName = server.GetString("name")
+				}
+				if r, err := svc.Servers.ForGame(game); err == nil && r.Identity() != "" {
+					if remoteGame, err := r.GetGame(command.Context(), game.GetString("game")); err == nil {
+						name = remoteGame.Name
+					}
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", game.Id, name, serverName, game.GetString("path"))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// NewSearchCommand returns the command browsing the games of the server.
+func NewSearchCommand(app core.App) *cobra.Command {
+	var query remote.GameQuery
+	var server string
+
+	command := &cobra.Command{
+		Use:          "search [name]",
+		Short:        "Searches the games of the server",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			svc, r, err := openRemote(command.Context(), app, server)
+			if err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				query.Search = args[0]
+			}
+			page, err := r.ListGames(command.Context(), query)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tYEAR\tRATING\tGENRES\tINSTALLED")
+			for _, game := range page.Items {
+				var year, rating string
+				if !game.Released.IsZero() {
+					year = strconv.Itoa(game.Released.Time().Year())
+				}
+				if game.Rating > 0 {
+					rating = strconv.FormatFloat(game.Rating, 'f', 0, 64)
+				}
+				installed := ""
+				if record, err := app.FindFirstRecordByData(svc.GamesCollection, "game", game.ID); err == nil {
+					installed = record.Id
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", game.ID, game.Name, year, rating, strings.Join(game.Genres, ", "), installed)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if page.TotalPages > 1 {
+				fmt.Printf("Page %d of %d, %d games\n", page.Page, page.TotalPages, page.TotalItems)
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&server, "server", "", "the name of the server, the default one if empty")
+	command.Flags().StringSliceVar(&query.Genres, "genre", nil, "only games having all of the genres")
+	command.Flags().StringSliceVar(&query.Statuses, "status", []string{remote.StatusFound}, "only games having one of the statuses")
+	command.Flags().Float64Var(&query.MinRating, "min-rating", 0, "only games rated at least as high, from 0 to 100")
+	command.Flags().IntVar(&query.Year, "year", 0, "only games released in the year")
+	command.Flags().StringVar(&query.Filter, "filter", "", "a PocketBase filter for anything else")
+	command.Flags().StringVar(&query.Sort, "sort", "", `the PocketBase sort, e. g. "-rating,name"`)
+	command.Flags().IntVar(&query.Page, "page", 1, "the page to show")
+	command.Flags().IntVar(&query.PerPage, "limit", 50, "the number of games per page")
+
+	return command
+}
+
+// NewInstallCommand returns the command downloading a game of the server. A running client takes over the download,
+// otherwise the command downloads the game itself and shows the progress until it is installed.
+func NewInstallCommand(app core.App) *cobra.Command {
+	var server, libraryPath string
+
+	command := &cobra.Command{
+		Use:          "install <server game id>",
+		Short:        "Downloads and installs a game of the server",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := callClient(http.MethodPost, "/api/download", url.Values{"id": {args[0]}, "server": {server}, "library": {libraryPath}}, nil)
+			if err == nil {
+				fmt.Println("The download was started by the running client, see status for its progress")
+				return nil
+			}
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+			return install(command.Context(), app, args[0], server, libraryPath)
+		},
+	}
+	command.Flags().StringVar(&server, "server", "", "the name of the server, the default one if empty")
+	command.Flags().StringVar(&libraryPath, "library", "", "the library to install into, the default library if empty")
+
+	return command
+}
+
+func install(ctx context.Context, app core.App, id, server, libraryPath string) error {
+	svc, r, err := openRemote(ctx, app, server)
+	if err != nil {
+		return err
+	}
+	if _, err := r.GetGame(ctx, id); err != nil {
+		return fmt.Errorf("game %s not found: %w", id, err)
+	}
+
+	record, err := svc.Downloads.Add(id, server, libraryPath)
+	if err != nil {
+		return err
+	}
+
+	records := make(chan *core.Record, 1)
+	records <- record
+	close(records)
+	done := make(chan struct{})
+	go func() {
+		svc.Downloads.Worker(records)
+		close(done)
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-ticker.C:
+			if current, err := app.FindRecordById(svc.DownloadsCollection, record.Id); err == nil {
+				fmt.Printf("\r\033[K%s", formatDownload(current))
+			}
+		case <-done:
+			running = false
+		}
+	}
+	fmt.Print("\r\033[K")
+
+	record, err = app.FindRecordById(svc.DownloadsCollection, record.Id)
+	if err != nil {
+		return err
+	}
+	if record.GetString("status") != "completed" {
+		return fmt.Errorf("download failed: %s", record.GetString("text"))
+	}
+
+	game, err := app.FindFirstRecordByData(svc.GamesCollection, "game", id)
+	if err != nil {
+		return fmt.Errorf("download completed, but the game wasn't added: %w", err)
+	}
+	fmt.Printf("Installed %s to %s\n", game.Id, game.GetString("path"))
+	if game.GetString("executable") == "" {
+		fmt.Println("No executable was found, pick one in the client before launching the game")
+	}
+	return nil
+}
+
+// NewUninstallCommand returns the command removing an installed game.
+func NewUninstallCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "uninstall <id>",
+		Short:        "Removes an installed game and its files",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := callClient(http.MethodDelete, "/api/game", url.Values{"id": {args[0]}}, nil)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+			return uninstall(app, args[0])
+		},
+	}
+}
+
+func uninstall(app core.App, id string) error {
+	svc, err := openServices(app)
+	if err != nil {
+		return err
+	}
+
+	game, err := app.FindRecordById(svc.GamesCollection, id)
+	if err != nil {
+		return fmt.Errorf("game %s is not installed", id)
+	}
+	// the game might have been started by another launch command
+	running, err := app.FindAllRecords(svc.SessionsCollection, dbx.HashExp{"game": game.GetString("game"), "running": true})
+	if err != nil {
+		return err
+	}
+	if len(running) > 0 {
+		return errors.New("game is running")
+	}
+
+	return svc.Downloads.Uninstall(game)
+}
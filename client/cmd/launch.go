@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// NewLaunchCommand returns the command shortcuts use to start a game. A running client starts the game itself, so it shows up
+// as running in the UI. Otherwise the game is started by the command, which waits until the game exited to record the session.
+func NewLaunchCommand(app core.App) *cobra.Command {
+	var profile string
+
+	command := &cobra.Command{
+		Use:          "launch <id>",
+		Short:        "Starts an installed game",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := launchInClient(args[0], profile)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+			return launch(command.Context(), app, args[0], profile)
+		},
+	}
+	command.Flags().StringVar(&profile, "profile", "", "the launch profile, the main profile if empty")
+
+	return command
+}
+
+func launchInClient(id, profile string) error {
+	return callClient(http.MethodPost, "/api/launch", url.Values{"id": {id}, "profile": {profile}}, nil)
+}
+
+func launch(ctx context.Context, app core.App, id, profile string) error {
+	svc, err := openServices(app)
+	if err != nil {
+		return err
+	}
+	// the game can be played offline
+	if err := svc.Authenticate(ctx); err != nil {
+		app.Logger().Warn("failed to log in", "error", err)
+	}
+	if err := svc.Tracker.CloseStale(); err != nil {
+		return err
+	}
+
+	game, err := app.FindRecordById(svc.GamesCollection, id)
+	if err != nil {
+		return fmt.Errorf("game %s is not installed", id)
+	}
+
+	if _, err := svc.Launch(ctx, game, profile); err != nil {
+		return err
+	}
+	svc.Tracker.Wait(game.GetString("game"))
+	svc.SyncPlaytime(ctx)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// NewLoginCommand returns the command logging into a server, which is how headless machines are set up.
+// The password is read from the terminal or from stdin and only the token is stored.
+func NewLoginCommand(app core.App) *cobra.Command {
+	var name, serverURL, email string
+
+	command := &cobra.Command{
+		Use:          "login",
+		Short:        "Logs into a server",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+			if password == "" {
+				return errors.New("password is required")
+			}
+
+			body := map[string]string{"server": name, "serverUrl": serverURL, "email": email, "password": password}
+			err = callClient(http.MethodPost, "/api/login", nil, body)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			_, err = svc.Servers.Login(command.Context(), name, serverURL, email, password)
+			return err
+		},
+	}
+	command.Flags().StringVar(&name, "name", "", "the name of the server, the host of the URL if empty")
+	command.Flags().StringVar(&serverURL, "server", "", "the URL of the server")
+	command.Flags().StringVar(&email, "email", "", "the email of the user")
+	command.MarkFlagRequired("server")
+	command.MarkFlagRequired("email")
+
+	return command
+}
+
+// NewRegisterCommand returns the command creating an account on a server with an invite, which logs into it like the login command.
+func NewRegisterCommand(app core.App) *cobra.Command {
+	var name, serverURL, invite, email string
+
+	command := &cobra.Command{
+		Use:          "register",
+		Short:        "Creates an account on a server with an invite",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+			if password == "" {
+				return errors.New("password is required")
+			}
+
+			body := map[string]string{"server": name, "serverUrl": serverURL, "invite": invite, "email": email, "password": password}
+			err = callClient(http.MethodPost, "/api/register", nil, body)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			_, err = svc.Servers.Register(command.Context(), name, serverURL, invite, email, password)
+			return err
+		},
+	}
+	command.Flags().StringVar(&name, "name", "", "the name of the server, the host of the URL if empty")
+	command.Flags().StringVar(&serverURL, "server", "", "the URL of the server")
+	command.Flags().StringVar(&invite, "invite", "", "the invite code from an admin of the server")
+	command.Flags().StringVar(&email, "email", "", "the email of the new account")
+	command.MarkFlagRequired("server")
+	command.MarkFlagRequired("invite")
+	command.MarkFlagRequired("email")
+
+	return command
+}
+
+// NewLogoutCommand returns the command removing the stored token of a server.
+func NewLogoutCommand(app core.App) *cobra.Command {
+	var server string
+
+	command := &cobra.Command{
+		Use:          "logout",
+		Short:        "Logs out of a server",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := callClient(http.MethodPost, "/api/logout", url.Values{"server": {server}}, nil)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			return svc.Servers.Logout(server)
+		},
+	}
+	command.Flags().StringVar(&server, "server", "", "the name of the server, the default one if empty")
+
+	return command
+}
+
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"boyl/pkg/discovery"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// NewServersCommand returns the command listing the server profiles, with subcommands to pick the default one and to remove them.
+// Profiles are added by the login command.
+func NewServersCommand(app core.App) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "servers",
+		Short:        "Lists the servers the client is set up for",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+
+			profiles, err := svc.Servers.List()
+			if err != nil {
+				return err
+			}
+			var defaultID string
+			if server, err := svc.Servers.Default(); err == nil {
+				defaultID = server.Id
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tURL\tEMAIL\tDEFAULT")
+			for _, server := range profiles {
+				var isDefault string
+				if server.Id == defaultID {
+					isDefault = "yes"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", server.Id, server.GetString("name"), server.GetString("url"), server.GetString("email"), isDefault)
+			}
+			return w.Flush()
+		},
+	}
+	command.AddCommand(newDefaultServerCommand(app), newRemoveServerCommand(app))
+
+	return command
+}
+
+func newDefaultServerCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "default <name>",
+		Short:        "Picks the server used when none is given",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			return svc.Servers.SetDefault(args[0])
+		},
+	}
+}
+
+func newRemoveServerCommand(app core.App) *cobra.Command {
+	return &cobra.Command{
+		Use:          "remove <name>",
+		Short:        "Logs out of a server and removes it, its games stay installed",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			err := callClient(http.MethodDelete, "/api/server", url.Values{"id": {args[0]}}, nil)
+			if !errors.Is(err, errNotRunning) {
+				return err
+			}
+
+			svc, err := openServices(app)
+			if err != nil {
+				return err
+			}
+			return svc.Servers.Remove(args[0])
+		},
+	}
+}
+
+// NewDiscoverCommand returns the command listing the servers on the local network, whose URLs can be used to log in.
+func NewDiscoverCommand() *cobra.Command {
+	var timeout time.Duration
+
+	command := &cobra.Command{
+		Use:          "discover",
+		Short:        "Lists the servers on the local network",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(command.Context(), timeout)
+			defer cancel()
+
+			found, err := discovery.Browse(ctx)
+			if err != nil {
+				return err
+			}
+			if len(found) == 0 {
+				fmt.Println("No servers found")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tVERSION\tURL")
+			for _, server := range found {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", server.Name, server.Version, server.URL)
+			}
+			return w.Flush()
+		},
+	}
+	command.Flags().DurationVar(&timeout, "timeout", discovery.BrowseTimeout, "how long to wait for answers")
+
+	return command
+}
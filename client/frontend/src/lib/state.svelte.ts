@@ -1,7 +1,25 @@
 import { subscribeMultiple } from './utils';
 import client from './client';
 import server from './remote';
-import type { ClientGame, ClientSettings, Download, Game, Setting, User } from './types';
+import type {
+	ClientGame,
+	ClientSettings,
+	DiscoveredServer,
+	Download,
+	ExecutableCandidate,
+	Game,
+	LaunchLog,
+	Library,
+	Playtime,
+	Runner,
+	SavePath,
+	Server,
+	ServerInfo,
+	Session,
+	Setting,
+	Snapshot,
+	User
+} from './types';
 
 class ClientState {
 	#rawSettings: Setting[] = $state([]);
@@ -10,11 +28,16 @@ class ClientState {
 			setup: false,
 			os: 'windows',
 			gamesDirectory: '',
+			libraries: [],
 			defaultLauncher: '',
-			serverUrl: '',
-			email: '',
-			password: '',
-			fitCovers: true
+			defaultRunner: '',
+			runners: [],
+			defaultServer: '',
+			fitCovers: true,
+			syncPlaytime: false,
+			saveRetention: 10,
+			syncSaves: false,
+			createShortcuts: false
 		};
 		this.#rawSettings.forEach((setting) => {
 			settings[setting.key] = setting.value;
@@ -22,8 +45,17 @@ class ClientState {
 
 		return settings;
 	});
+	servers: Server[] = $state([]);
+	// the UI shows the games of one server at a time, the default one of the client
+	server: Server | undefined = $derived(
+		this.servers.find((server) => server.id === this.settings.defaultServer) ?? this.servers[0]
+	);
 	downloads: Download[] = $state([]);
 	games: ClientGame[] = $state([]);
+	sessions: Session[] = $state([]);
+	running: string[] = $derived(
+		this.sessions.filter((session) => session.running).map((session) => session.game)
+	);
 
 	async load() {
 		const settingsUnsubscribe = await subscribeMultiple(
@@ -32,6 +64,12 @@ class ClientState {
 			(settings) => (this.#rawSettings = settings),
 			'*'
 		);
+		const serversUnsubscribe = await subscribeMultiple(
+			client.collection('servers'),
+			() => this.servers,
+			(servers) => (this.servers = servers),
+			'*'
+		);
 		const downloadsUnsubscribe = await subscribeMultiple(
 			client.collection('downloads'),
 			() => this.downloads,
@@ -45,10 +83,19 @@ class ClientState {
 			'*'
 		);
 
+		const sessionsUnsubscribe = await subscribeMultiple(
+			client.collection('sessions'),
+			() => this.sessions,
+			(sessions) => (this.sessions = sessions),
+			'*'
+		);
+
 		return () => {
 			settingsUnsubscribe();
+			serversUnsubscribe();
 			downloadsUnsubscribe();
 			gamesUnsubscribe();
+			sessionsUnsubscribe();
 		};
 	}
 
@@ -64,8 +111,88 @@ class ClientState {
 		}
 	}
 
-	async addDownload(id: string) {
-		const res = await client.send(`/api/download?id=${id}`, {
+	// only the client keeps the password, the UI gets a token to talk to the server.
+	// The profile named server is created if it doesn't exist yet.
+	async login(
+		server: string,
+		serverUrl: string,
+		email: string,
+		password: string
+	): Promise<{ server: string; token: string }> {
+		return await client.send('/api/login', {
+			method: 'POST',
+			body: { server, serverUrl, email, password }
+		});
+	}
+
+	// creates an account with an invite from an admin of the server
+	async register(
+		server: string,
+		serverUrl: string,
+		invite: string,
+		email: string,
+		password: string
+	): Promise<{ server: string; token: string }> {
+		return await client.send('/api/register', {
+			method: 'POST',
+			body: { server, serverUrl, invite, email, password }
+		});
+	}
+
+	async logout(server?: string) {
+		const params = new URLSearchParams();
+		if (server) {
+			params.set('server', server);
+		}
+		await client.send(`/api/logout?${params}`, {
+			method: 'POST'
+		});
+	}
+
+	async getToken(server?: string): Promise<string> {
+		const params = new URLSearchParams();
+		if (server) {
+			params.set('server', server);
+		}
+		const res: { token: string } = await client.send(`/api/token?${params}`, {
+			method: 'GET'
+		});
+		return res.token;
+	}
+
+	// the warning is empty if the client fully supports the server
+	async getServerInfo(server?: string): Promise<{ info: ServerInfo; warning: string }> {
+		const params = new URLSearchParams();
+		if (server) {
+			params.set('server', server);
+		}
+		return await client.send(`/api/server/info?${params}`, {
+			method: 'GET'
+		});
+	}
+
+	// servers announcing themselves on the local network
+	async discoverServers(): Promise<DiscoveredServer[]> {
+		return await client.send('/api/discover', {
+			method: 'GET'
+		});
+	}
+
+	async removeServer(id: string) {
+		await client.send(`/api/server?id=${id}`, {
+			method: 'DELETE'
+		});
+	}
+
+	async addDownload(id: string, library?: string, server?: string) {
+		const params = new URLSearchParams({ id });
+		if (library) {
+			params.set('library', library);
+		}
+		if (server) {
+			params.set('server', server);
+		}
+		const res = await client.send(`/api/download?${params}`, {
 			method: 'POST'
 		});
 		if (!res.ok) {
@@ -83,8 +210,94 @@ class ClientState {
 		}
 	}
 
-	async launchGame(id: string) {
-		const res = await client.send(`/api/launch?id=${id}`, {
+	async uninstallGame(id: string) {
+		const res = await client.send(`/api/game?id=${id}`, {
+			method: 'DELETE'
+		});
+
+		if (!res.ok) {
+			throw new Error('Failed to uninstall game');
+		}
+	}
+
+	async getLibraries(): Promise<Library[]> {
+		return await client.send('/api/libraries', {
+			method: 'GET'
+		});
+	}
+
+	async moveGame(id: string, library: string) {
+		const params = new URLSearchParams({ id, library });
+		await client.send(`/api/move?${params}`, {
+			method: 'POST'
+		});
+	}
+
+	async getLaunchLog(id: string, lines = 50): Promise<LaunchLog> {
+		return await client.send(`/api/launch/log?id=${id}&lines=${lines}`, {
+			method: 'GET'
+		});
+	}
+
+	async getSavePaths(id: string): Promise<SavePath[]> {
+		return await client.send(`/api/saves/paths?id=${id}`, {
+			method: 'GET'
+		});
+	}
+
+	async getSnapshots(game: string): Promise<Snapshot[]> {
+		return await client.collection('snapshots').getFullList({
+			filter: client.filter('game = {:game}', { game }),
+			sort: '-created'
+		});
+	}
+
+	async snapshotSaves(id: string): Promise<Snapshot> {
+		return await client.send(`/api/saves/snapshot?id=${id}`, {
+			method: 'POST'
+		});
+	}
+
+	async restoreSnapshot(id: string) {
+		await client.send(`/api/saves/restore?id=${id}`, {
+			method: 'POST'
+		});
+	}
+
+	async syncSaves(id: string, keep?: 'local' | 'remote'): Promise<ClientGame> {
+		const params = new URLSearchParams({ id });
+		if (keep) {
+			params.set('keep', keep);
+		}
+		return await client.send(`/api/saves/sync?${params}`, {
+			method: 'POST'
+		});
+	}
+
+	async getRunners(): Promise<Runner[]> {
+		return await client.send('/api/runners', {
+			method: 'GET'
+		});
+	}
+
+	async getPlaytime(): Promise<Playtime[]> {
+		return await client.send('/api/playtime', {
+			method: 'GET'
+		});
+	}
+
+	async getExecutables(id: string): Promise<ExecutableCandidate[]> {
+		return await client.send(`/api/executables?id=${id}`, {
+			method: 'GET'
+		});
+	}
+
+	async launchGame(id: string, profile?: string) {
+		const params = new URLSearchParams({ id });
+		if (profile) {
+			params.set('profile', profile);
+		}
+		const res = await client.send(`/api/launch?${params}`, {
 			method: 'POST'
 		});
 
@@ -8,10 +8,12 @@ export interface Game extends Base {
 	path: string;
 	name: string;
 	status: 'deleted' | 'invalid' | 'missing' | 'found';
+	corrupt: boolean;
 	version: string;
 	summary: string;
 	released: string;
 	rating: number;
+	ageRating: number;
 	genres: string[];
 	cover: string;
 	artworks: string[];
@@ -20,10 +22,108 @@ export interface Game extends Base {
 	providerId: string;
 }
 
+export interface LaunchProfile {
+	name: string;
+	executable: string;
+	args: string;
+}
+
+export interface Server extends Base {
+	name: string;
+	url: string;
+	email: string;
+}
+
+export interface DiscoveredServer {
+	name: string;
+	version: string;
+	url: string;
+}
+
+export interface ServerInfo {
+	version: string;
+	protocol: number;
+	minProtocol: number;
+	formats: string[];
+	features: string[];
+	legacy?: boolean;
+}
+
 export interface ClientGame extends Base {
 	game: string;
+	server: string;
 	path: string;
+	launcher: string;
 	executable: string;
+	args: string;
+	profiles: LaunchProfile[] | null;
+	runner: string;
+	env: Record<string, string> | null;
+	dllOverrides: string;
+	workingDirectory: string;
+	preLaunch: string;
+	postExit: string;
+	savePaths: string[] | null;
+	saveVersion: number;
+	saveHash: string;
+	saveConflict: boolean;
+	shortcuts: string[] | null;
+}
+
+export interface SavePath {
+	pattern: string;
+	path: string;
+	exists: boolean;
+}
+
+export interface Snapshot extends Base {
+	game: string;
+	file: string;
+	hash: string;
+	size: number;
+	files: number;
+	reason: 'session' | 'manual' | 'restore';
+}
+
+export interface Runner {
+	name: string;
+	kind: 'wine' | 'proton';
+	path: string;
+}
+
+export interface ExecutableCandidate {
+	path: string;
+	args: string;
+	name: string;
+	score: number;
+}
+
+export interface Session extends Base {
+	game: string;
+	server: string;
+	profile: string;
+	start: string;
+	end: string;
+	duration: number;
+	exitCode: number;
+	running: boolean;
+	synced: boolean;
+	crashed: boolean;
+	log: string;
+}
+
+export interface LaunchLog {
+	session: Session;
+	lines: string[] | null;
+}
+
+export interface Playtime {
+	game: string;
+	total: number;
+	recent: number;
+	sessions: number;
+	lastPlayed: string;
+	running: boolean;
 }
 
 export interface Status {
@@ -35,14 +135,24 @@ export interface Status {
 
 export interface Download extends Base {
 	game: string;
-	status: 'starting' | 'downloading' | 'extracting' | 'completed' | 'failed';
+	server: string;
+	status: 'starting' | 'downloading' | 'extracting' | 'moving' | 'completed' | 'failed';
 	active: boolean;
+	library: string;
+	source: string;
 	text: string;
 	speed: number;
 	progress: number;
 	total: number;
 }
 
+export interface Library {
+	path: string;
+	free: number;
+	total: number;
+	error?: string;
+}
+
 export interface Setting extends Base {
 	key: string;
 	value: string;
@@ -52,12 +162,17 @@ export interface ClientSettings {
 	[key: string]: unknown;
 	os: 'windows' | 'linux' | 'darwin';
 	gamesDirectory: string;
+	libraries: string[];
 	defaultLauncher: string;
+	defaultRunner: string;
+	runners: Runner[];
 	setup: boolean;
-	serverUrl: string;
-	email: string;
-	password: string;
+	defaultServer: string;
 	fitCovers: boolean;
+	syncPlaytime: boolean;
+	saveRetention: number;
+	syncSaves: boolean;
+	createShortcuts: boolean;
 }
 
 export interface User extends Base {
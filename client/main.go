@@ -1,20 +1,27 @@
 package main
 
 import (
+	"boyl/client/cmd"
 	"boyl/client/frontend"
 	"boyl/client/pkg/download"
+	"boyl/client/pkg/launch"
+	"boyl/client/pkg/library"
 	"boyl/client/pkg/remote"
-	"boyl/client/pkg/settings"
+	"boyl/client/pkg/saves"
+	"boyl/client/pkg/servers"
+	"boyl/client/pkg/services"
+	"boyl/pkg/discovery"
+	"context"
 	"errors"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/google/shlex"
-
+	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
@@ -24,22 +31,6 @@ import (
 	_ "boyl/client/migrations"
 )
 
-func getRemote(s *settings.Settings) (*remote.Client, error) {
-	serverURL := s.GetString("serverUrl")
-	email := s.GetString("email")
-	password := s.GetString("password")
-	r := remote.New(serverURL)
-
-	if email != "" && password != "" {
-		err := r.Authenticate(email, password)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return r, nil
-}
-
 func main() {
 	app := pocketbase.New()
 
@@ -48,13 +39,20 @@ func main() {
 		Automigrate: isGoRun,
 	})
 
-	downloadsChannel := make(chan *core.Record, 20)
-
-	app.OnRecordCreate("downloads").BindFunc(func(e *core.RecordEvent) error {
-		downloadsChannel <- e.Record
-
-		return e.Next()
-	})
+	app.RootCmd.AddCommand(cmd.NewArchiveCommand())
+	app.RootCmd.AddCommand(cmd.NewLoginCommand(app))
+	app.RootCmd.AddCommand(cmd.NewRegisterCommand(app))
+	app.RootCmd.AddCommand(cmd.NewLogoutCommand(app))
+	app.RootCmd.AddCommand(cmd.NewServersCommand(app))
+	app.RootCmd.AddCommand(cmd.NewDiscoverCommand())
+	app.RootCmd.AddCommand(cmd.NewListCommand(app))
+	app.RootCmd.AddCommand(cmd.NewSearchCommand(app))
+	app.RootCmd.AddCommand(cmd.NewInstallCommand(app))
+	app.RootCmd.AddCommand(cmd.NewStatusCommand(app))
+	app.RootCmd.AddCommand(cmd.NewCancelCommand(app))
+	app.RootCmd.AddCommand(cmd.NewLaunchCommand(app))
+	app.RootCmd.AddCommand(cmd.NewUninstallCommand(app))
+	cmd.TrackFailures(app.RootCmd)
 
 	app.OnTerminate().BindFunc(func(te *core.TerminateEvent) error {
 		downloadsCollection, err := app.FindCollectionByNameOrId("downloads")
@@ -85,27 +83,48 @@ func main() {
 		subFS := apis.MustSubFS(frontend.Assets, "build")
 		se.Router.GET("/{path...}", apis.Static(subFS, true))
 
-		downloadsCollection, err := app.FindCollectionByNameOrId("downloads")
+		svc, err := services.New(app)
 		if err != nil {
 			return err
 		}
-		settingsCollection, err := app.FindCollectionByNameOrId("settings")
-		if err != nil {
-			return err
+		// installed games can be played offline, logging in again is up to the user if the token was rejected
+		if err := svc.Authenticate(context.Background()); err != nil {
+			app.Logger().Warn("failed to log in", "error", err)
 		}
-		gamesCollection, err := app.FindCollectionByNameOrId("games")
-		if err != nil {
-			return err
+		s := svc.Settings
+		registry := svc.Servers
+		m := svc.Downloads
+		tracker := svc.Tracker
+		saveManager := svc.Saves
+		downloadsCollection := svc.DownloadsCollection
+		gamesCollection := svc.GamesCollection
+		snapshotsCollection := svc.SnapshotsCollection
+
+		if err := m.CleanStaging(); err != nil {
+			app.Logger().Error("failed to clean staging files", "error", err)
 		}
+		// only the served client processes downloads, the commands run their own worker
+		downloadsChannel := make(chan *core.Record, 20)
+		app.OnRecordCreate("downloads").BindFunc(func(e *core.RecordEvent) error {
+			downloadsChannel <- e.Record
 
-		r, err := getRemote(settings.NewSettings(app, settingsCollection))
-		if err != nil {
-			return err
+			return e.Next()
+		})
+		go m.Worker(downloadsChannel)
+
+		if err := tracker.CloseStale(); err != nil {
+			app.Logger().Error("failed to close stale sessions", "error", err)
 		}
 
-		s := settings.NewSettings(app, settingsCollection)
-		m := download.NewManager(app, downloadsCollection, gamesCollection, s, r)
-		go m.Worker(downloadsChannel)
+		go svc.SyncPlaytime(context.Background())
+
+		app.OnRecordAfterUpdateSuccess("sessions").BindFunc(func(e *core.RecordEvent) error {
+			if !e.Record.GetBool("running") && !e.Record.GetBool("synced") {
+				go svc.SyncPlaytime(context.Background())
+			}
+
+			return e.Next()
+		})
 
 		downloads, err := app.FindAllRecords(downloadsCollection)
 		if err != nil {
@@ -119,17 +138,136 @@ func main() {
 			return err
 		}
 
-		se.Router.GET("/api/update-remote", func(e *core.RequestEvent) error {
-			serverURL := s.GetString("serverUrl")
-			email := s.GetString("email")
-			password := s.GetString("password")
+		se.Router.POST("/api/login", func(e *core.RequestEvent) error {
+			var body struct {
+				// Server is the name of the profile, which is created if it doesn't exist yet
+				Server    string `json:"server"`
+				ServerURL string `json:"serverUrl"`
+				Email     string `json:"email"`
+				Password  string `json:"password"`
+			}
+			if err := e.BindBody(&body); err != nil {
+				return e.BadRequestError("invalid body", err)
+			}
+			if body.ServerURL == "" || body.Email == "" || body.Password == "" {
+				return e.BadRequestError("serverUrl, email and password are required", nil)
+			}
 
-			r.URL = serverURL
-			if email != "" && password != "" {
-				err := r.Authenticate(email, password)
-				if err != nil {
-					return err
-				}
+			server, err := registry.Login(e.Request.Context(), body.Server, body.ServerURL, body.Email, body.Password)
+			if errors.Is(err, remote.ErrUnauthorized) {
+				return e.UnauthorizedError("invalid email or password", nil)
+			}
+			if err != nil {
+				return remoteError(e, "failed to log in", err)
+			}
+			r, err := registry.Client(server.Id)
+			if err != nil {
+				return err
+			}
+
+			return e.JSON(200, map[string]string{"server": server.Id, "token": r.Token()})
+		})
+
+		se.Router.POST("/api/register", func(e *core.RequestEvent) error {
+			var body struct {
+				Server    string `json:"server"`
+				ServerURL string `json:"serverUrl"`
+				Invite    string `json:"invite"`
+				Email     string `json:"email"`
+				Password  string `json:"password"`
+			}
+			if err := e.BindBody(&body); err != nil {
+				return e.BadRequestError("invalid body", err)
+			}
+			if body.ServerURL == "" || body.Invite == "" || body.Email == "" || body.Password == "" {
+				return e.BadRequestError("serverUrl, invite, email and password are required", nil)
+			}
+
+			server, err := registry.Register(e.Request.Context(), body.Server, body.ServerURL, body.Invite, body.Email, body.Password)
+			// the server says what is wrong, e. g. an expired invite or a too short password
+			var apiErr *remote.Error
+			if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+				return e.BadRequestError(apiErr.Message, apiErr.Data)
+			}
+			if err != nil {
+				return remoteError(e, "failed to register", err)
+			}
+			r, err := registry.Client(server.Id)
+			if err != nil {
+				return err
+			}
+
+			return e.JSON(200, map[string]string{"server": server.Id, "token": r.Token()})
+		})
+
+		se.Router.POST("/api/logout", func(e *core.RequestEvent) error {
+			if err := registry.Logout(e.Request.URL.Query().Get("server")); err != nil {
+				return remoteError(e, "failed to log out", err)
+			}
+			return e.JSON(200, "")
+		})
+
+		// the UI talks to a server itself with the token of the client, which is empty if there is no such server
+		se.Router.GET("/api/token", func(e *core.RequestEvent) error {
+			if !services.Local(e.Request) {
+				return e.ForbiddenError("the token is only handed to the UI of the client", nil)
+			}
+			r, err := registry.Client(e.Request.URL.Query().Get("server"))
+			if errors.Is(err, servers.ErrNotFound) {
+				return e.JSON(200, map[string]string{"token": ""})
+			}
+			if err != nil {
+				return err
+			}
+			return e.JSON(200, map[string]string{"token": r.Token()})
+		})
+
+		// what the UI should warn about, since it talks to the server itself as well
+		se.Router.GET("/api/server/info", func(e *core.RequestEvent) error {
+			r, err := registry.Client(e.Request.URL.Query().Get("server"))
+			if err != nil {
+				return remoteError(e, "failed to get server", err)
+			}
+
+			info := r.Info()
+			var connectErr error
+			if info == nil {
+				info, connectErr = r.Connect(e.Request.Context())
+			}
+			if info == nil {
+				return remoteError(e, "failed to get server info", connectErr)
+			}
+			warning := remote.Warning(info)
+			if err := remote.Check(info); err != nil {
+				warning = err.Error()
+			}
+			return e.JSON(200, map[string]any{"info": info, "warning": warning})
+		})
+
+		// servers on the local network, so the user can pick one instead of typing its URL
+		se.Router.GET("/api/discover", func(e *core.RequestEvent) error {
+			ctx, cancel := context.WithTimeout(e.Request.Context(), discovery.BrowseTimeout)
+			defer cancel()
+
+			found, err := discovery.Browse(ctx)
+			if err != nil {
+				return e.InternalServerError("failed to discover servers", err)
+			}
+			if found == nil {
+				found = []discovery.Server{}
+			}
+
+			return e.JSON(200, found)
+		})
+
+		se.Router.DELETE("/api/server", func(e *core.RequestEvent) error {
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+
+			if err := registry.Remove(id); err != nil {
+				return remoteError(e, "failed to remove server", err)
 			}
 
 			return e.JSON(200, "")
@@ -147,46 +285,198 @@ func main() {
 				return e.NotFoundError("game not found", nil)
 			}
 
-			executable := game.GetString("executable")
-			if executable == "" {
-				return e.BadRequestError("executable is required", nil)
+			session, err := svc.Launch(e.Request.Context(), game, q.Get("profile"))
+			if errors.Is(err, launch.ErrProfileNotFound) {
+				return e.NotFoundError("profile not found", err)
+			}
+			if errors.Is(err, services.ErrInvalidLaunch) {
+				return e.BadRequestError(err.Error(), err)
+			}
+			if errors.Is(err, saves.ErrConflict) {
+				return e.Error(http.StatusConflict, "saves changed both locally and on the server", nil)
+			}
+			if errors.Is(err, launch.ErrAlreadyRunning) {
+				return e.Error(http.StatusConflict, "game is already running", nil)
+			}
+			if err != nil {
+				return e.InternalServerError("failed to start", err)
+			}
+
+			return e.JSON(200, session)
+		})
+
+		se.Router.GET("/api/launch/log", func(e *core.RequestEvent) error {
+			q := e.Request.URL.Query()
+			id := q.Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+			lines, err := strconv.Atoi(q.Get("lines"))
+			if err != nil || lines <= 0 {
+				lines = 50
 			}
 
-			userArgs, err := shlex.Split(game.GetString("args"))
+			game, err := app.FindRecordById(gamesCollection, id)
 			if err != nil {
-				return e.BadRequestError("failed to parse args", err)
+				return e.NotFoundError("game not found", nil)
+			}
+
+			session, err := tracker.LastSession(game.GetString("game"))
+			if err != nil {
+				return e.NotFoundError("game has not been launched yet", nil)
+			}
+
+			output, err := launch.TailLog(session.GetString("log"), lines)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return e.InternalServerError("failed to read log", err)
+			}
+
+			return e.JSON(200, map[string]any{
+				"session": session,
+				"lines":   output,
+			})
+		})
+
+		se.Router.GET("/api/saves/paths", func(e *core.RequestEvent) error {
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
 			}
 
-			launcherString := game.GetString("launcher")
-			if launcherString == "" {
-				launcherString = s.GetString("defaultLauncher")
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
 			}
-			launcher, err := shlex.Split(launcherString)
+
+			paths, err := saveManager.Paths(game)
 			if err != nil {
-				return e.BadRequestError("failed to parse launcher", err)
+				return e.BadRequestError(err.Error(), err)
 			}
 
-			var name string
-			var args []string
+			return e.JSON(200, paths)
+		})
 
-			if len(launcher) > 0 {
-				name = launcher[0]
-				args = append(launcher[1:], executable)
-			} else {
-				name = executable
+		se.Router.POST("/api/saves/snapshot", func(e *core.RequestEvent) error {
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
 			}
-			args = append(args, userArgs...)
 
-			cmd := exec.Command(name, args...)
-			cmd.Env = os.Environ()
-			if err := cmd.Start(); err != nil {
-				return e.InternalServerError("failed to start", err)
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+
+			snapshot, err := saveManager.Snapshot(game, "manual")
+			if errors.Is(err, saves.ErrNoSavePaths) {
+				return e.BadRequestError(err.Error(), err)
+			}
+			if err != nil {
+				return e.InternalServerError("failed to snapshot saves", err)
+			}
+
+			return e.JSON(200, snapshot)
+		})
+
+		se.Router.POST("/api/saves/restore", func(e *core.RequestEvent) error {
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+
+			snapshot, err := app.FindRecordById(snapshotsCollection, id)
+			if err != nil {
+				return e.NotFoundError("snapshot not found", nil)
+			}
+			game, err := app.FindFirstRecordByData(gamesCollection, "game", snapshot.GetString("game"))
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+			if tracker.IsRunning(game.GetString("game")) {
+				return e.Error(http.StatusConflict, "game is running", nil)
+			}
+
+			if err := saveManager.Restore(snapshot, game); err != nil {
+				return e.InternalServerError("failed to restore saves", err)
 			}
-			cmd.Process.Release()
 
 			return e.JSON(200, "")
 		})
 
+		se.Router.POST("/api/saves/sync", func(e *core.RequestEvent) error {
+			q := e.Request.URL.Query()
+			id := q.Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+			resolution := saves.Resolution(q.Get("keep"))
+			if resolution != saves.ResolveNone && resolution != saves.KeepLocal && resolution != saves.KeepRemote {
+				return e.BadRequestError("keep must be local or remote", nil)
+			}
+
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+			if tracker.IsRunning(game.GetString("game")) {
+				return e.Error(http.StatusConflict, "game is running", nil)
+			}
+
+			r, err := registry.ForGame(game)
+			if err != nil {
+				return remoteError(e, "failed to sync saves", err)
+			}
+			err = saveManager.Sync(e.Request.Context(), r, game, resolution)
+			if errors.Is(err, saves.ErrConflict) {
+				return e.Error(http.StatusConflict, err.Error(), nil)
+			}
+			if err != nil {
+				return remoteError(e, "failed to sync saves", err)
+			}
+
+			return e.JSON(200, game)
+		})
+
+		se.Router.GET("/api/runners", func(e *core.RequestEvent) error {
+			return e.JSON(200, launch.Runners(s))
+		})
+
+		se.Router.GET("/api/playtime", func(e *core.RequestEvent) error {
+			playtime, err := tracker.Playtime(time.Now().AddDate(0, 0, -14))
+			if err != nil {
+				return err
+			}
+
+			return e.JSON(200, playtime)
+		})
+
+		se.Router.GET("/api/executables", func(e *core.RequestEvent) error {
+			q := e.Request.URL.Query()
+			id := q.Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+
+			var title string
+			if r, err := registry.ForGame(game); err == nil {
+				if remoteGame, err := r.GetGame(e.Request.Context(), game.GetString("game")); err == nil {
+					title = remoteGame.Name
+				}
+			}
+
+			candidates, err := download.FindExecutables(game.GetString("path"), title)
+			if err != nil {
+				return e.InternalServerError("failed to find executables", err)
+			}
+
+			return e.JSON(200, candidates)
+		})
+
 		se.Router.POST("/api/download", func(e *core.RequestEvent) error {
 			q := e.Request.URL.Query()
 			id := q.Get("id")
@@ -194,9 +484,60 @@ func main() {
 				return e.BadRequestError("id is required", nil)
 			}
 
+			_, err := m.Add(id, q.Get("server"), q.Get("library"))
+			if errors.Is(err, download.ErrLibraryNotFound) || errors.Is(err, servers.ErrNotFound) {
+				return e.BadRequestError(err.Error(), nil)
+			}
+			if err != nil {
+				return err
+			}
+
+			return e.JSON(200, "")
+		})
+
+		se.Router.GET("/api/libraries", func(e *core.RequestEvent) error {
+			return e.JSON(200, library.List(s))
+		})
+
+		se.Router.POST("/api/move", func(e *core.RequestEvent) error {
+			q := e.Request.URL.Query()
+			id := q.Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+			libraryPath := q.Get("library")
+			if !library.Contains(s, libraryPath) {
+				return e.BadRequestError("library not found", nil)
+			}
+
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+
+			// earlier downloads are replayed on startup and would point the game back to its old location
+			previous, err := app.FindAllRecords(downloadsCollection, dbx.HashExp{"game": game.GetString("game")})
+			if err != nil {
+				return err
+			}
+			for _, download := range previous {
+				status := download.GetString("status")
+				if status != "completed" && status != "failed" {
+					return e.BadRequestError("game has a download in progress", nil)
+				}
+			}
+			for _, download := range previous {
+				if err := m.Forget(download.Id); err != nil {
+					return err
+				}
+			}
+
 			download := core.NewRecord(downloadsCollection)
-			download.Set("game", id)
-			download.Set("status", "starting")
+			download.Set("game", game.GetString("game"))
+			download.Set("server", game.GetString("server"))
+			download.Set("status", "moving")
+			download.Set("library", libraryPath)
+			download.Set("source", game.GetString("path"))
 			if err := app.Save(download); err != nil {
 				return err
 			}
@@ -204,6 +545,31 @@ func main() {
 			return e.JSON(200, "")
 		})
 
+		se.Router.DELETE("/api/game", func(e *core.RequestEvent) error {
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+
+			game, err := app.FindRecordById(gamesCollection, id)
+			if err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+			if tracker.IsRunning(game.GetString("game")) {
+				return e.Error(http.StatusConflict, "game is running", nil)
+			}
+
+			err = m.Uninstall(game)
+			if errors.Is(err, download.ErrDownloadInProgress) {
+				return e.Error(http.StatusConflict, err.Error(), nil)
+			}
+			if err != nil {
+				return e.InternalServerError("failed to uninstall game", err)
+			}
+
+			return e.JSON(200, "")
+		})
+
 		se.Router.DELETE("/api/download", func(e *core.RequestEvent) error {
 			q := e.Request.URL.Query()
 			id := q.Get("id")
@@ -227,7 +593,7 @@ func main() {
 
 			w.SetTitle("Boyl")
 			w.SetSize(480, 320, webview.HintMin)
-			w.Navigate("http://localhost:48658")
+			w.Navigate("http://" + services.Address)
 			w.Run()
 			w.Destroy()
 			os.Exit(0)
@@ -238,7 +604,7 @@ func main() {
 			log.Fatal(err)
 		}
 		err = apis.Serve(app, apis.ServeConfig{
-			HttpAddr:        "localhost:48658",
+			HttpAddr:        services.Address,
 			ShowStartBanner: false,
 		})
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -249,5 +615,27 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if cmd.Failed() {
+			os.Exit(1)
+		}
+	}
+}
+
+// remoteError responds with a status telling the UI why a request to the server failed, e. g. to ask the user to log in again.
+func remoteError(e *core.RequestEvent, message string, err error) error {
+	switch {
+	case errors.Is(err, servers.ErrNotFound):
+		return e.NotFoundError("server not found", err)
+	case errors.Is(err, remote.ErrUnauthorized):
+		return e.UnauthorizedError("not logged in", err)
+	case errors.Is(err, remote.ErrNotFound):
+		return e.NotFoundError("not found on the server", err)
+	case errors.Is(err, remote.ErrUnavailable):
+		return e.Error(http.StatusServiceUnavailable, "server unavailable", err)
+	case errors.Is(err, remote.ErrVersionMismatch):
+		return e.Error(http.StatusBadGateway, "server version isn't supported", err)
+	case errors.Is(err, remote.ErrUnsupported):
+		return e.Error(http.StatusNotImplemented, "not supported by the server", err)
 	}
+	return e.InternalServerError(message, err)
 }
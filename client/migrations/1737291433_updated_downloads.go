@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_794313261")
+		if err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(11, []byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "text190089999",
+			"max": 0,
+			"min": 0,
+			"name": "path",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// completed downloads are replayed on every start, they have to keep pointing to where the games are installed
+		games, err := app.FindCollectionByNameOrId("games")
+		if err != nil {
+			return err
+		}
+		downloads, err := app.FindAllRecords(collection, dbx.HashExp{"status": "completed"})
+		if err != nil {
+			return err
+		}
+		for _, download := range downloads {
+			game, err := app.FindFirstRecordByData(games, "game", download.GetString("game"))
+			if err != nil || game.GetString("path") == "" {
+				continue
+			}
+			download.Set("path", game.GetString("path"))
+			if err := app.Save(download); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_794313261")
+		if err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("text190089999")
+
+		return app.Save(collection)
+	})
+}
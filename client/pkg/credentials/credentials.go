@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store keeps the token of a server login outside of the database.
+type Store interface {
+	// Get returns the stored token, empty if there is none.
+	Get() (string, error)
+	// Set stores token, an empty token removes it.
+	Set(token string) error
+}
+
+// New returns the store for the token of a server of the client using dataDirectory, which is the keyring of the system if there is one.
+// The token is kept in a file only the user can read otherwise. An empty server is the single login of earlier versions.
+func New(dataDirectory, server string) Store {
+	path := filepath.Join(dataDirectory, "token")
+	if server != "" {
+		path = filepath.Join(dataDirectory, "tokens", server)
+	}
+	file := &FileStore{Path: path}
+	keyring := newKeyring(dataDirectory, server)
+	if keyring == nil {
+		return file
+	}
+	return &fallbackStore{primary: keyring, fallback: file}
+}
+
+// FileStore keeps the token in a file, which only the user can read.
+type FileStore struct {
+	Path string
+}
+
+func (f *FileStore) Get() (string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (f *FileStore) Set(token string) error {
+	if token == "" {
+		err := os.Remove(f.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	// write a new file, since the mode of an existing one isn't changed by WriteFile
+	temp := f.Path + ".tmp"
+	if err := os.WriteFile(temp, []byte(token), 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, f.Path)
+}
+
+// fallbackStore uses the fallback while the primary store fails, e. g. on a headless machine without a running keyring.
+type fallbackStore struct {
+	primary  Store
+	fallback Store
+}
+
+func (s *fallbackStore) Get() (string, error) {
+	if token, err := s.primary.Get(); err == nil && token != "" {
+		return token, nil
+	}
+	return s.fallback.Get()
+}
+
+func (s *fallbackStore) Set(token string) error {
+	if err := s.primary.Set(token); err != nil {
+		return s.fallback.Set(token)
+	}
+	// there must not be an outdated copy left in the file
+	return s.fallback.Set("")
+}
@@ -0,0 +1,69 @@
+package credentials_test
+
+import (
+	"boyl/client/pkg/credentials"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store := &credentials.FileStore{Path: filepath.Join(t.TempDir(), "data", "token")}
+
+	token, err := store.Get()
+	if err != nil || token != "" {
+		t.Fatalf("expected no token, got %q and %v", token, err)
+	}
+
+	for _, value := range []string{"first", "second"} {
+		if err := store.Set(value); err != nil {
+			t.Fatal(err)
+		}
+		if token, err := store.Get(); err != nil || token != value {
+			t.Fatalf("expected %q, got %q and %v", value, token, err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(store.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expected the token to only be readable by the user, got %s", info.Mode())
+		}
+	}
+
+	if err := store.Set(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.Path); !os.IsNotExist(err) {
+		t.Errorf("expected the token to be removed, got %v", err)
+	}
+	// logging out twice is fine
+	if err := store.Set(""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewKeepsServersApart(t *testing.T) {
+	if _, err := exec.LookPath("secret-tool"); err == nil {
+		t.Skip("the keyring of the system would be used")
+	}
+	dir := t.TempDir()
+
+	if err := credentials.New(dir, "home").Set("home-token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.New(dir, "office").Set("office-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	for server, expected := range map[string]string{"home": "home-token", "office": "office-token", "": ""} {
+		if token, err := credentials.New(dir, server).Get(); err != nil || token != expected {
+			t.Errorf("expected %q for server %q, got %q and %v", expected, server, token, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// secretService stores the token in the Secret Service, e. g. GNOME Keyring or KWallet, through secret-tool.
+type secretService struct {
+	account string
+	server  string
+}
+
+func newKeyring(dataDirectory, server string) Store {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	// clients using different data directories log in separately
+	account, err := filepath.Abs(dataDirectory)
+	if err != nil {
+		account = dataDirectory
+	}
+	return &secretService{account: account, server: server}
+}
+
+// attributes identify the token in the keyring. Lookups match items having more attributes too,
+// so the single login of earlier versions must be cleared before the first server token is stored.
+func (s *secretService) attributes() []string {
+	attributes := []string{"service", "boyl", "account", s.account}
+	if s.server != "" {
+		attributes = append(attributes, "server", s.server)
+	}
+	return attributes
+}
+
+func (s *secretService) run(stdin string, args ...string) (string, error) {
+	// without a session bus, secret-tool might wait for one to start
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "secret-tool", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	return strings.TrimSpace(stdout.String()), err
+}
+
+func (s *secretService) Get() (string, error) {
+	token, err := s.run("", append([]string{"lookup"}, s.attributes()...)...)
+	// lookup also fails if there is no token
+	if _, ok := err.(*exec.ExitError); ok {
+		return "", nil
+	}
+	return token, err
+}
+
+func (s *secretService) Set(token string) error {
+	if token == "" {
+		_, err := s.run("", append([]string{"clear"}, s.attributes()...)...)
+		return err
+	}
+	_, err := s.run(token, append([]string{"store", "--label=Boyl server login"}, s.attributes()...)...)
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package credentials
+
+// newKeyring returns nil, since only the Secret Service of Linux is supported so far.
+func newKeyring(dataDirectory, server string) Store {
+	return nil
+}
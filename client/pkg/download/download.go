@@ -1,11 +1,15 @@
 package download
 
 import (
-	"boyl/client/pkg/archive"
+	"boyl/client/pkg/library"
 	"boyl/client/pkg/remote"
+	"boyl/client/pkg/runner"
 	"boyl/client/pkg/settings"
+	"boyl/pkg/archive"
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,42 +19,78 @@ import (
 )
 
 type Download struct {
-	record         *core.Record
-	app            core.App
-	settings       *settings.Settings
-	remote         *remote.Client
-	game           *remote.Game
-	gamesDirectory string
-	baseDirectory  string
-	archivePath    string
-	ctx            context.Context
-	cancel         context.CancelFunc
+	record        *core.Record
+	app           core.App
+	settings      *settings.Settings
+	remote        *remote.Client
+	game          *remote.Game
+	library       string
+	baseDirectory string
+	stagingPath   string
+	archivePath   string
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
-func NewDownload(record *core.Record, app core.App, settings *settings.Settings, remote *remote.Client) (*Download, error) {
-	gamesDirectory := settings.GetString("gamesDirectory")
-
-	game, err := remote.GetGame(record.GetString("game"))
-	if err != nil {
-		return nil, err
+func NewDownload(record *core.Record, app core.App, settings *settings.Settings, r *remote.Client) (*Download, error) {
+	libraryPath := record.GetString("library")
+	if libraryPath == "" {
+		libraryPath = library.Default(settings)
+		record.Set("library", libraryPath)
+	}
+	if libraryPath == "" {
+		return nil, errors.New("no library configured")
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	// a move only needs what is known locally, so it works while the server is offline
+	game := &remote.Game{ID: record.GetString("game")}
+	if record.GetString("source") == "" {
+		var err error
+		game, err = r.GetGame(ctx, record.GetString("game"))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// the path is kept once it was chosen, so replaying the download doesn't move the game if its folder would be named differently now
+	path := record.GetString("path")
+	if path == "" {
+		path = installPath(app, record, libraryPath, game)
+		record.Set("path", path)
+	}
 
 	return &Download{
-		record:         record,
-		app:            app,
-		settings:       settings,
-		remote:         remote,
-		game:           game,
-		gamesDirectory: gamesDirectory,
-		baseDirectory:  filepath.Join(gamesDirectory, game.Name),
-		archivePath:    filepath.Join(gamesDirectory, game.Name, game.ID+".tmp"),
-		ctx:            ctx,
-		cancel:         cancel,
+		record:        record,
+		app:           app,
+		settings:      settings,
+		remote:        r,
+		game:          game,
+		library:       libraryPath,
+		baseDirectory: path,
+		stagingPath:   stagingDirectory(libraryPath, record.Id),
+		archivePath:   stagingArchivePath(libraryPath, record.Id),
+		ctx:           ctx,
+		cancel:        cancel,
 	}, nil
 }
 
+// installPath chooses the directory a download installs the game to. A moved game keeps the name of its folder
+// and a reinstall goes to where the game is installed already, if that is in the same library.
+func installPath(app core.App, record *core.Record, libraryPath string, game *remote.Game) string {
+	if source := record.GetString("source"); source != "" {
+		return filepath.Join(libraryPath, filepath.Base(source))
+	}
+	if installed, err := app.FindFirstRecordByData("games", "game", game.ID); err == nil {
+		path := installed.GetString("path")
+		if path != "" && filepath.Clean(filepath.Dir(path)) == filepath.Clean(libraryPath) {
+			return path
+		}
+	}
+	return filepath.Join(libraryPath, library.FolderName(game.Name))
+}
+
 func (d *Download) Start() error {
 	status := d.record.GetString("status")
 	if status == "starting" {
@@ -94,6 +134,31 @@ func (d *Download) Start() error {
 	if status == "extracting" {
 		err := d.extract()
 		if err != nil {
+			// a partial install is of no use and a malicious archive might have filled the disk
+			if err := os.RemoveAll(d.stagingPath); err != nil {
+				d.app.Logger().Error("failed to clean up partial install", "path", d.stagingPath, "error", err)
+			}
+
+			d.record.Set("status", "failed")
+			d.record.Set("text", err.Error())
+			d.app.Save(d.record)
+			return err
+		}
+
+		d.record.Set("status", "completed")
+		d.record.Set("progress", 1)
+		err = d.app.Save(d.record)
+		if err != nil {
+			return err
+		}
+	}
+	if status == "moving" {
+		err := d.move()
+		if err != nil {
+			if err := os.RemoveAll(d.stagingPath); err != nil {
+				d.app.Logger().Error("failed to clean up partial move", "path", d.stagingPath, "error", err)
+			}
+
 			d.record.Set("status", "failed")
 			d.record.Set("text", err.Error())
 			d.app.Save(d.record)
@@ -111,6 +176,11 @@ func (d *Download) Start() error {
 	return nil
 }
 
+// IsMove reports whether the download moves an installed game to another library instead of installing it.
+func (d *Download) IsMove() bool {
+	return d.record.GetString("source") != ""
+}
+
 func (d *Download) download() error {
 	d.record.Set("status", "downloading")
 	d.app.Save(d.record)
@@ -125,8 +195,8 @@ func (d *Download) download() error {
 		return err
 	}
 
-	resp := client.Do(req)
-	d.record.Set("total", resp.HTTPResponse.ContentLength)
+	resp := client.Do(req.WithContext(d.ctx))
+	d.record.Set("total", max(resp.Size(), 0))
 	d.app.Save(d.record)
 
 	t := time.NewTicker(500 * time.Millisecond)
@@ -145,11 +215,39 @@ loop:
 		}
 	}
 
-	if err := resp.Err(); err != nil {
-		return err
+	return downloadError(resp)
+}
+
+// downloadError turns the errors of grab into the errors of the remote package.
+func downloadError(resp *grab.Response) error {
+	err := resp.Err()
+	var status grab.StatusCodeError
+	if errors.As(err, &status) && resp.HTTPResponse != nil {
+		return remote.StatusError(resp.HTTPResponse)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", remote.ErrUnavailable, err)
 	}
+	return err
+}
 
-	return nil
+// limits returns the extraction limits, where each default can be overridden in the settings.
+func (d *Download) limits() archive.Limits {
+	limits := archive.DefaultLimits
+	if v := d.settings.GetNumber("extractMaxBytes"); v > 0 {
+		limits.MaxTotalBytes = uint64(v)
+	}
+	if v := d.settings.GetNumber("extractMaxFiles"); v > 0 {
+		limits.MaxFiles = uint64(v)
+	}
+	if v := d.settings.GetNumber("extractMaxRatio"); v > 0 {
+		limits.MaxRatio = v
+	}
+	if v := d.settings.GetNumber("extractMaxPathLength"); v > 0 {
+		limits.MaxPathLength = int(v)
+	}
+	return limits
 }
 
 func (d *Download) extract() error {
@@ -169,7 +267,7 @@ func (d *Download) extract() error {
 
 	size := info.Size()
 
-	extractor, err := archive.NewExtractor(filepath.Base(d.game.Path), file, size)
+	extractor, err := archive.NewExtractor(filepath.Base(d.game.Path), file, size, d.limits())
 	if err != nil {
 		return err
 	}
@@ -181,29 +279,45 @@ func (d *Download) extract() error {
 	d.record.Set("total", progressSize)
 	d.app.Save(d.record)
 
+	// an earlier extraction might have been interrupted
+	if err := os.RemoveAll(d.stagingPath); err != nil {
+		return err
+	}
+
+	err = extractor.Extract(d.ctx, d.stagingPath, d.trackProgress(progressSize))
+	if err != nil {
+		return err
+	}
+
+	// the wine prefix holds saves and settings, which must survive an update
+	err = swapDirectories(d.app.Logger(), d.stagingPath, d.baseDirectory, backupDirectory(d.library, d.record.Id), runner.DataDirectory)
+	if err != nil {
+		return err
+	}
+
+	// closing the file for windows because its a shitty os
+	file.Close()
+	// the archive is only removed now, so an interrupted swap can extract it again. One that is left behind is removed by CleanStaging.
+	if err := os.Remove(d.archivePath); err != nil {
+		d.app.Logger().Error("failed to remove archive", "path", d.archivePath, "error", err)
+	}
+	return nil
+}
+
+// trackProgress returns a progress callback, which receives the bytes processed so far and periodically saves the progress and speed to the record.
+func (d *Download) trackProgress(total uint64) func(uint64) {
 	movingAverage := NewMovingAverage(5 * time.Second)
 	lastProgress := time.Now()
 	var lastValue uint64
 
-	err = extractor.Extract(d.ctx, d.baseDirectory, func(u uint64) {
+	return func(u uint64) {
 		if time.Since(lastProgress) > 500*time.Millisecond {
 			movingAverage.Add(float64(u - lastValue))
 			lastValue = u
-			d.record.Set("progress", float64(u)/float64(progressSize))
+			d.record.Set("progress", float64(u)/float64(total))
 			d.record.Set("speed", movingAverage.Get())
 			d.app.Save(d.record)
 			lastProgress = time.Now()
 		}
-	})
-	if err != nil {
-		return err
 	}
-
-	// closing the file for windows because its a shitty os
-	file.Close()
-	if err := os.Remove(d.archivePath); err != nil {
-		return err
-	}
-
-	return nil
 }
@@ -0,0 +1,342 @@
+package download
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/shlex"
+)
+
+// Candidate is a file that might start a game, ranked by how likely it is the one the user wants.
+type Candidate struct {
+	Path string `json:"path"`
+	Args string `json:"args"`
+	// Name is set when the candidate is declared by metadata shipped with the game, e. g. a GOG play task.
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+var (
+	// deniedNames matches installers, redistributables and crash handlers, which are never the game itself.
+	deniedNames = regexp.MustCompile(`^(unins\d*|uninstall.*|setup.*|install|installer|.*redist.*|dxsetup|dxwebsetup|dotnet.*|ndp\d+.*|oalinst|physx.*|.*prereq.*|.*crash.*|.*bugreport.*|.*errorreport.*|easyanticheat_setup|notification_helper)$`)
+	// deniedDirectories only contain redistributables and support files.
+	deniedDirectories = []string{"_commonredist", "commonredist", "redist", "redistributables", "__installer", "__redist", "__support", "directx", "vcredist", "prerequisites", "easyanticheat"}
+	// toolNames are executables shipped with a game that are not the game, but might be useful as an additional profile.
+	toolNames = regexp.MustCompile(`(editor|config|settings|setup|server|benchmark|tool)`)
+	// executableExtensions are extensions of files that can be executed, besides extensionless binaries.
+	executableExtensions = []string{".exe", ".bat", ".cmd", ".sh", ".x86", ".x86_64", ".appimage", ".bin", ".run"}
+	// wordSeparator splits names into words for matching them against the title.
+	wordSeparator = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+func isExecutable(info os.FileInfo) bool {
+	ext := strings.ToLower(filepath.Ext(info.Name()))
+	if ext == ".exe" || ext == ".bat" || ext == ".cmd" {
+		return true
+	}
+	if info.Mode()&0111 == 0 {
+		return false
+	}
+	// shared libraries usually have the executable bit set
+	return ext == "" || slices.Contains(executableExtensions, ext)
+}
+
+// normalizeName lowercases the name and removes everything but letters and numbers.
+func normalizeName(name string) string {
+	return wordSeparator.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// titleScore scores how well the file name matches the title of the game.
+func titleScore(name, title string) int {
+	normalizedName := normalizeName(name)
+	normalizedTitle := normalizeName(title)
+	if normalizedName == "" || normalizedTitle == "" {
+		return 0
+	}
+	if normalizedName == normalizedTitle {
+		return 40
+	}
+	if len(normalizedName) >= 3 && (strings.Contains(normalizedTitle, normalizedName) || strings.Contains(normalizedName, normalizedTitle)) {
+		return 20
+	}
+
+	score := 0
+	titleWords := wordSeparator.Split(strings.ToLower(title), -1)
+	for _, word := range wordSeparator.Split(strings.ToLower(name), -1) {
+		if len(word) >= 3 && slices.Contains(titleWords, word) {
+			score += 5
+		}
+	}
+	return min(score, 15)
+}
+
+// scoreFile scores a file only by its name, location and size.
+func scoreFile(rel string, size int64, title string) int {
+	base := filepath.Base(rel)
+	ext := strings.ToLower(filepath.Ext(base))
+	name := strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+	segments := strings.Split(strings.ToLower(filepath.ToSlash(rel)), "/")
+
+	score := 0
+	if deniedNames.MatchString(name) {
+		score -= 100
+	}
+	for _, segment := range segments[:len(segments)-1] {
+		if slices.Contains(deniedDirectories, segment) {
+			score -= 50
+			break
+		}
+	}
+	if toolNames.MatchString(name) {
+		score -= 15
+	}
+
+	score += titleScore(name, title)
+	score -= 5 * (len(segments) - 1)
+
+	switch {
+	case size >= 1<<20:
+		score += 10
+	case size < 64<<10:
+		score -= 10
+	}
+
+	switch {
+	case base == "start.sh":
+		// GOG linux installers start the game through this script, which sets up the environment
+		score += 60
+	case ext == ".sh" || ext == ".bat" || ext == ".cmd":
+		score -= 10
+	}
+
+	return score
+}
+
+// FindExecutables returns the files in directory that might start the game, best candidate first.
+// Besides scoring files by name, location and size, GOG goggame-*.info files and .desktop files are used.
+func FindExecutables(directory, title string) ([]Candidate, error) {
+	candidates := make(map[string]*Candidate)
+	var gogInfos, desktopFiles []string
+
+	err := filepath.WalkDir(directory, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name := strings.ToLower(d.Name())
+		if strings.HasPrefix(name, "goggame-") && strings.HasSuffix(name, ".info") {
+			gogInfos = append(gogInfos, p)
+		}
+		if strings.HasSuffix(name, ".desktop") {
+			desktopFiles = append(desktopFiles, p)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || !isExecutable(info) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(directory, p)
+		if err != nil {
+			return err
+		}
+		candidates[p] = &Candidate{
+			Path:  p,
+			Score: scoreFile(rel, info.Size(), title),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// adds a candidate declared by metadata, the file itself might lack the executable bit
+	declare := func(path, args, name string, score int) {
+		if !isWithinDirectory(directory, path) {
+			return
+		}
+		candidate, ok := candidates[path]
+		if !ok {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				return
+			}
+			rel, _ := filepath.Rel(directory, path)
+			candidate = &Candidate{Path: path, Score: scoreFile(rel, info.Size(), title)}
+			candidates[path] = candidate
+		}
+		candidate.Score += score
+		if candidate.Args == "" {
+			candidate.Args = args
+		}
+		if candidate.Name == "" {
+			candidate.Name = name
+		}
+	}
+
+	for _, p := range gogInfos {
+		tasks, err := parseGogInfo(p)
+		if err != nil {
+			continue
+		}
+		for _, task := range tasks {
+			score := 10
+			if task.IsPrimary {
+				score = 100
+			}
+			declare(task.Path, task.Arguments, task.Name, score)
+		}
+	}
+
+	for _, p := range desktopFiles {
+		entry, err := parseDesktopFile(p)
+		if err != nil {
+			continue
+		}
+		declare(entry.Path, entry.Args, entry.Name, 60)
+	}
+
+	result := make([]Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, *candidate)
+	}
+	slices.SortFunc(result, func(a, b Candidate) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return result, nil
+}
+
+// FindExecutablePath returns the most likely executable of the game in directory.
+func FindExecutablePath(directory, title string) (string, error) {
+	candidates, err := FindExecutables(directory, title)
+	if err != nil || len(candidates) == 0 {
+		return "", err
+	}
+	return candidates[0].Path, nil
+}
+
+func isWithinDirectory(directory, path string) bool {
+	rel, err := filepath.Rel(directory, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+type gogPlayTask struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Path      string `json:"path"`
+	Arguments string `json:"arguments"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// parseGogInfo returns the file play tasks of a goggame-*.info file with their paths resolved.
+func parseGogInfo(path string) ([]gogPlayTask, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		PlayTasks []gogPlayTask `json:"playTasks"`
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	var tasks []gogPlayTask
+	for _, task := range info.PlayTasks {
+		// url tasks link to manuals and forums
+		if task.Type != "FileTask" || task.Path == "" {
+			continue
+		}
+		task.Path = filepath.Join(filepath.Dir(path), filepath.FromSlash(strings.ReplaceAll(task.Path, `\`, "/")))
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+type desktopEntry struct {
+	Name string
+	Path string
+	Args string
+}
+
+// parseDesktopFile returns the command of a .desktop file. Absolute paths usually point to where the game was installed
+// when the archive was created, so they are looked up next to the .desktop file.
+func parseDesktopFile(path string) (*desktopEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entry desktopEntry
+	var exec string
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		if section != "[Desktop Entry]" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Name":
+			entry.Name = value
+		case "Exec":
+			exec = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	fields, err := shlex.Split(exec)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, field := range fields {
+		// field codes like %f and %U are replaced by the desktop environment
+		if strings.HasPrefix(field, "%") {
+			continue
+		}
+		args = append(args, field)
+	}
+	if len(args) == 0 {
+		return nil, fs.ErrNotExist
+	}
+
+	command := args[0]
+	if !filepath.IsAbs(command) {
+		command = filepath.Join(filepath.Dir(path), command)
+	} else if _, err := os.Stat(command); err != nil {
+		command = filepath.Join(filepath.Dir(path), filepath.Base(command))
+	}
+	entry.Path = command
+	entry.Args = strings.Join(args[1:], " ")
+
+	return &entry, nil
+}
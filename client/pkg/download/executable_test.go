@@ -0,0 +1,148 @@
+package download_test
+
+import (
+	"boyl/client/pkg/download"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type file struct {
+	name string
+	size int
+	mode os.FileMode
+	data string
+}
+
+func writeTree(t *testing.T, files []file) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		data := []byte(f.data)
+		if f.size > 0 {
+			data = make([]byte, f.size)
+		}
+		mode := f.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(path, data, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindExecutablePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		files    []file
+		expected string
+	}{
+		{
+			name:  "uninstaller",
+			title: "Hollow Knight",
+			files: []file{
+				{name: "unins000.exe", size: 3 << 20},
+				{name: "hollow_knight.exe", size: 600 << 10},
+				{name: "UnityCrashHandler64.exe", size: 1 << 20},
+			},
+			expected: "hollow_knight.exe",
+		},
+		{
+			name:  "redist",
+			title: "Anno 1800",
+			files: []file{
+				{name: "_CommonRedist/vcredist/2019/vc_redist.x64.exe", size: 14 << 20},
+				{name: "Bin/Win64/Anno1800.exe", size: 80 << 20},
+				{name: "Setup.exe", size: 2 << 20},
+			},
+			expected: "Bin/Win64/Anno1800.exe",
+		},
+		{
+			name:  "shared libraries",
+			title: "Some Game",
+			files: []file{
+				{name: "libsteam_api.so", size: 2 << 20, mode: 0755},
+				{name: "game.x86_64", size: 1 << 20, mode: 0755},
+			},
+			expected: "game.x86_64",
+		},
+		{
+			name:  "gog linux",
+			title: "Stardew Valley",
+			files: []file{
+				{name: "start.sh", data: "#!/bin/sh", mode: 0755},
+				{name: "support/postinst.sh", data: "#!/bin/sh", mode: 0755},
+				{name: "game/StardewValley", size: 1 << 20, mode: 0755},
+			},
+			expected: "start.sh",
+		},
+		{
+			name:  "gog info",
+			title: "Heroes of Might and Magic 3",
+			files: []file{
+				{name: "HD_Launcher.exe", size: 2 << 20},
+				{name: "Heroes3.exe", size: 2 << 20},
+				{name: "goggame-1207658787.info", data: `{"playTasks": [
+					{"isPrimary": true, "type": "FileTask", "name": "HoMM 3", "path": "bin\\h3.exe", "arguments": "-windowed"},
+					{"type": "URLTask", "name": "Support", "link": "https://www.gog.com/support"}
+				]}`},
+				{name: "bin/h3.exe", size: 1 << 20},
+			},
+			expected: "bin/h3.exe",
+		},
+		{
+			name:  "desktop file",
+			title: "Other",
+			files: []file{
+				{name: "game.desktop", data: "[Desktop Entry]\nName=Game\nExec=\"/opt/elsewhere/run-game\" %U\n"},
+				{name: "run-game", data: "#!/bin/sh", mode: 0755},
+				{name: "engine", size: 4 << 20, mode: 0755},
+			},
+			expected: "run-game",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTree(t, test.files)
+			path, err := download.FindExecutablePath(dir, test.title)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := filepath.Join(dir, filepath.FromSlash(test.expected))
+			if path != expected {
+				candidates, _ := download.FindExecutables(dir, test.title)
+				t.Errorf("expected %s, got %s (candidates: %+v)", expected, path, candidates)
+			}
+		})
+	}
+}
+
+func TestFindExecutablesGogProfiles(t *testing.T) {
+	dir := writeTree(t, []file{
+		{name: "goggame-1.info", data: `{"playTasks": [
+			{"isPrimary": true, "type": "FileTask", "name": "Game", "path": "game.exe"},
+			{"type": "FileTask", "name": "Settings", "path": "config.exe", "arguments": "/safe"}
+		]}`},
+		{name: "game.exe", size: 1 << 20},
+		{name: "config.exe", size: 1 << 20},
+	})
+
+	candidates, err := download.FindExecutables(dir, "Game")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %+v", candidates)
+	}
+	if candidates[0].Name != "Game" || candidates[1].Name != "Settings" || candidates[1].Args != "/safe" {
+		t.Errorf("unexpected candidates %+v", candidates)
+	}
+}
@@ -0,0 +1,7 @@
+package download
+
+var SwapDirectories = swapDirectories
+
+var CopyTree = copyTree
+
+var TreeSize = treeSize
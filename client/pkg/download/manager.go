@@ -1,32 +1,41 @@
 package download
 
 import (
+	"boyl/client/pkg/launch"
+	"boyl/client/pkg/library"
 	"boyl/client/pkg/remote"
+	"boyl/client/pkg/servers"
 	"boyl/client/pkg/settings"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/pocketbase/pocketbase/core"
 )
 
+// ErrLibraryNotFound is returned for downloads into a directory that isn't one of the configured libraries.
+var ErrLibraryNotFound = errors.New("library not found")
+
 type Manager struct {
 	app                 core.App
 	downloadsCollection *core.Collection
 	gamesCollection     *core.Collection
 	settings            *settings.Settings
-	remote              *remote.Client
+	servers             *servers.Registry
 
 	mu        sync.Mutex
 	downloads map[string]*Download
 }
 
-func NewManager(app core.App, downloadsCollection *core.Collection, gamesCollection *core.Collection, settings *settings.Settings, remote *remote.Client) *Manager {
+func NewManager(app core.App, downloadsCollection *core.Collection, gamesCollection *core.Collection, settings *settings.Settings, servers *servers.Registry) *Manager {
 	return &Manager{
 		app:                 app,
 		downloadsCollection: downloadsCollection,
 		gamesCollection:     gamesCollection,
 		settings:            settings,
-		remote:              remote,
+		servers:             servers,
 		mu:                  sync.Mutex{},
 		downloads:           make(map[string]*Download),
 	}
@@ -38,9 +47,12 @@ func (m *Manager) Worker(records chan *core.Record) {
 			m.app.Logger().Error("nil record")
 			continue
 		}
-		download, err := NewDownload(record, m.app, m.settings, m.remote)
+		download, err := m.newDownload(record)
 		if err != nil {
 			m.app.Logger().Error("failed to create download", "error", err)
+			if isInProgress(record.GetString("status")) {
+				m.failDownload(record, err)
+			}
 			continue
 		}
 
@@ -58,34 +70,164 @@ func (m *Manager) Worker(records chan *core.Record) {
 		if err != nil {
 			game = core.NewRecord(m.gamesCollection)
 		}
+		previousPath := game.GetString("path")
 		game.Set("game", download.game.ID)
+		game.Set("server", record.GetString("server"))
 		game.Set("path", download.baseDirectory)
 
-		executable := download.game.Executable
-		if executable == "" {
-			executable, err = FindExecutablePath(download.baseDirectory)
-			if err != nil {
-				m.app.Logger().Error("failed to find executable", "error", err)
-				continue
+		if download.IsMove() {
+			// keep what the user picked, just in its new location
+			game.Set("executable", rebasePath(game.GetString("executable"), previousPath, download.baseDirectory))
+			game.Set("workingDirectory", rebasePath(game.GetString("workingDirectory"), previousPath, download.baseDirectory))
+			profiles := launch.Profiles(game)[1:]
+			for i := range profiles {
+				profiles[i].Executable = rebasePath(profiles[i].Executable, previousPath, download.baseDirectory)
 			}
+			game.Set("profiles", profiles)
+		} else if err := m.detectProfiles(game, download); err != nil {
+			m.app.Logger().Error("failed to find executable", "error", err)
+			continue
 		}
-		game.Set("executable", executable)
 
 		err = m.app.Save(game)
 		if err != nil {
 			m.app.Logger().Error("failed to save game", "error", err)
 			continue
 		}
+
+		// a moved game keeps its shortcuts, they start it by its id
+		if !download.IsMove() {
+			if err := m.createShortcuts(game, download.remote, download.game); err != nil {
+				m.app.Logger().Error("failed to create shortcuts", "game", game.Id, "error", err)
+			}
+		}
+	}
+}
+
+// newDownload prepares a download with the client of the server it is from.
+func (m *Manager) newDownload(record *core.Record) (*Download, error) {
+	r, err := m.servers.ForGame(record)
+	if err != nil {
+		return nil, err
 	}
+	return NewDownload(record, m.app, m.settings, r)
 }
 
+// failDownload shows why a download couldn't be started. It is picked up again on the next start, unless the game is gone for good.
+func (m *Manager) failDownload(record *core.Record, err error) {
+	if !errors.Is(err, remote.ErrUnavailable) && !errors.Is(err, remote.ErrUnauthorized) {
+		record.Set("status", "failed")
+	}
+	record.Set("text", err.Error())
+	if err := m.app.Save(record); err != nil {
+		m.app.Logger().Error("failed to save download", "error", err)
+	}
+}
+
+// detectProfiles sets the executable and launch profiles of a freshly installed game. An executable that still exists is kept,
+// since downloads are processed again on every start.
+func (m *Manager) detectProfiles(game *core.Record, download *Download) error {
+	if download.game.Executable != "" {
+		game.Set("executable", download.game.Executable)
+		return nil
+	}
+
+	executable := game.GetString("executable")
+	if executable != "" && isWithinDirectory(download.baseDirectory, executable) {
+		if _, err := os.Stat(executable); err == nil {
+			return nil
+		}
+	}
+
+	candidates, err := FindExecutables(download.baseDirectory, download.game.Name)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		game.Set("executable", "")
+		return nil
+	}
+
+	game.Set("executable", candidates[0].Path)
+	if game.GetString("args") == "" {
+		game.Set("args", candidates[0].Args)
+	}
+
+	if len(launch.Profiles(game)) > 1 {
+		return nil
+	}
+	// named candidates come from metadata shipped with the game, e. g. the config tool of a GOG release
+	var profiles []launch.Profile
+	for _, candidate := range candidates[1:] {
+		if candidate.Name == "" {
+			continue
+		}
+		profiles = append(profiles, launch.Profile{
+			Name:       candidate.Name,
+			Executable: candidate.Path,
+			Args:       candidate.Args,
+		})
+	}
+	game.Set("profiles", profiles)
+
+	return nil
+}
+
+// rebasePath moves path from the old base directory to the new one. Paths outside of the old base directory are returned unchanged.
+func rebasePath(path, oldBase, newBase string) string {
+	if path == "" || oldBase == "" || !isWithinDirectory(oldBase, path) {
+		return path
+	}
+	rel, err := filepath.Rel(oldBase, path)
+	if err != nil {
+		return path
+	}
+	return filepath.Join(newBase, rel)
+}
+
+// Add creates a download installing a game of a server into a library. server is the id or name of a server profile,
+// the default one if empty, and the default library is used if libraryPath is empty.
+// Processing starts once the record reaches a worker.
+func (m *Manager) Add(gameID, server, libraryPath string) (*core.Record, error) {
+	if libraryPath == "" {
+		libraryPath = library.Default(m.settings)
+	}
+	if !library.Contains(m.settings, libraryPath) {
+		return nil, ErrLibraryNotFound
+	}
+	profile, err := m.servers.Find(server)
+	if err != nil {
+		return nil, err
+	}
+
+	record := core.NewRecord(m.downloadsCollection)
+	record.Set("game", gameID)
+	record.Set("server", profile.Id)
+	record.Set("status", "starting")
+	record.Set("library", libraryPath)
+	if err := m.app.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Cancel stops a download and marks it as failed, or removes it if it already finished.
+// Downloads that no worker of this process knows about, e. g. ones left behind by a closed client, are handled the same way.
 func (m *Manager) Cancel(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	download, ok := m.downloads[id]
 	if !ok {
-		return fmt.Errorf("download %s not found", id)
+		record, err := m.app.FindRecordById(m.downloadsCollection, id)
+		if err != nil {
+			return fmt.Errorf("download %s not found", id)
+		}
+		if isInProgress(record.GetString("status")) {
+			record.Set("status", "failed")
+			return m.app.Save(record)
+		}
+		return m.app.Delete(record)
 	}
 
 	status := download.record.GetString("status")
@@ -98,3 +240,61 @@ func (m *Manager) Cancel(id string) error {
 	download.record.Set("status", "failed")
 	return m.app.Save(download.record)
 }
+
+// Forget removes a download that isn't in progress from the history, so it isn't replayed on the next start anymore.
+func (m *Manager) Forget(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, err := m.app.FindRecordById(m.downloadsCollection, id)
+	if err != nil {
+		return fmt.Errorf("download %s not found", id)
+	}
+	if isInProgress(record.GetString("status")) {
+		return fmt.Errorf("download %s is in progress", id)
+	}
+	delete(m.downloads, id)
+	return m.app.Delete(record)
+}
+
+// CleanStaging removes staging files left behind by downloads that are no longer in progress, e.g. after a crash.
+func (m *Manager) CleanStaging() error {
+	for _, path := range library.Paths(m.settings) {
+		if err := m.cleanStaging(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) cleanStaging(libraryPath string) error {
+	entries, err := os.ReadDir(libraryPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		id, ok := stagingDownloadID(entry.Name())
+		if !ok {
+			continue
+		}
+
+		record, err := m.app.FindRecordById(m.downloadsCollection, id)
+		if err == nil {
+			if isInProgress(record.GetString("status")) {
+				continue
+			}
+		}
+
+		path := filepath.Join(libraryPath, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		m.app.Logger().Info("removed stale staging file", "path", path)
+	}
+
+	return nil
+}
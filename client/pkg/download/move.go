@@ -0,0 +1,149 @@
+package download
+
+import (
+	"boyl/pkg/archive"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// move moves an installed game into the library of the download. Within the same filesystem the folder is renamed,
+// otherwise it is copied into a staging directory first so an interrupted move never leaves a partial install behind.
+func (d *Download) move() error {
+	d.record.Set("status", "moving")
+	d.app.Save(d.record)
+
+	source := d.record.GetString("source")
+	if filepath.Clean(source) == filepath.Clean(d.baseDirectory) {
+		return nil
+	}
+
+	_, err := os.Stat(source)
+	if errors.Is(err, os.ErrNotExist) {
+		// the move might have finished right before the client was closed
+		if _, err := os.Stat(d.baseDirectory); err == nil {
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(d.baseDirectory); err == nil {
+		return fmt.Errorf("%s already exists", d.baseDirectory)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := os.MkdirAll(d.library, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(source, d.baseDirectory); err == nil {
+		return nil
+	}
+
+	total, err := treeSize(source)
+	if err != nil {
+		return err
+	}
+	d.record.Set("total", total)
+	d.app.Save(d.record)
+
+	// an earlier move might have been interrupted
+	if err := os.RemoveAll(d.stagingPath); err != nil {
+		return err
+	}
+
+	if err := copyTree(d.ctx, source, d.stagingPath, d.trackProgress(total)); err != nil {
+		return err
+	}
+
+	if err := swapDirectories(d.app.Logger(), d.stagingPath, d.baseDirectory, backupDirectory(d.library, d.record.Id)); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(source)
+}
+
+// treeSize returns the size of all regular files in the directory.
+func treeSize(directory string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	return size, err
+}
+
+// copyTree copies the directory including symlinks and file modes. progress receives the bytes copied so far.
+func copyTree(ctx context.Context, source, destination string, progress func(uint64)) error {
+	var copied uint64
+
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destination, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(destPath, info.Mode().Perm()|0700)
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, destPath)
+		case entry.Type().IsRegular():
+			return copyFile(ctx, path, destPath, info.Mode(), func(n uint64) {
+				copied += n
+				progress(copied)
+			})
+		default:
+			// sockets, devices and the like are not part of a game
+			return nil
+		}
+	})
+}
+
+func copyFile(ctx context.Context, source, destination string, mode fs.FileMode, progress func(uint64)) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := archive.CopyBufferWithProgress(ctx, dst, src, nil, nil, progress); err != nil {
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,125 @@
+package download_test
+
+import (
+	"boyl/client/pkg/download"
+	"boyl/pkg/testapp"
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestCopyTree(t *testing.T) {
+	files := map[string]string{
+		"game.exe":          "MZ",
+		"data/level1.pak":   "level one",
+		"data/maps/map.dat": "map",
+		"empty.txt":         "",
+	}
+	source := t.TempDir()
+	writeFiles(t, source, files)
+	if err := os.Chmod(filepath.Join(source, "game.exe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(source, "saves"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	symlinks := runtime.GOOS != "windows"
+	if symlinks {
+		if err := os.Symlink("level1.pak", filepath.Join(source, "data", "current.pak")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size, err := download.TreeSize(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 14 {
+		t.Errorf("expected the size of the regular files to be 14, got %d", size)
+	}
+
+	destination := filepath.Join(t.TempDir(), "copy")
+	var progress uint64
+	if err := download.CopyTree(context.Background(), source, destination, func(copied uint64) {
+		progress = copied
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if progress != size {
+		t.Errorf("expected the progress to end at %d, got %d", size, progress)
+	}
+
+	expected := maps.Clone(files)
+	if symlinks {
+		expected["data/current.pak"] = "level one"
+		target, err := os.Readlink(filepath.Join(destination, "data", "current.pak"))
+		if err != nil || target != "level1.pak" {
+			t.Errorf("expected the symlink to be copied, got %q, %v", target, err)
+		}
+	}
+	if copied := readFiles(t, destination); !maps.Equal(copied, expected) {
+		t.Errorf("expected %v, got %v", expected, copied)
+	}
+	if info, err := os.Stat(filepath.Join(destination, "saves")); err != nil || !info.IsDir() {
+		t.Errorf("expected the empty directory to be copied, got %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(filepath.Join(destination, "game.exe")); err != nil || info.Mode().Perm() != 0755 {
+			t.Errorf("expected the file mode to be kept, got %v", info.Mode())
+		}
+	}
+}
+
+func TestCopyTreeCanceled(t *testing.T) {
+	source := t.TempDir()
+	writeFiles(t, source, map[string]string{"game.exe": "MZ"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := download.CopyTree(ctx, source, filepath.Join(t.TempDir(), "copy"), func(uint64) {})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewDownloadMove(t *testing.T) {
+	app := testapp.New(t)
+	downloads, err := app.FindCollectionByNameOrId("downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"keeps the folder name", "", filepath.Join("library", "Game: Remastered")},
+		{"keeps the recorded path", filepath.Join("library", "Game"), filepath.Join("library", "Game")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := core.NewRecord(downloads)
+			record.Set("game", "g1")
+			record.Set("status", "moving")
+			record.Set("library", "library")
+			record.Set("source", filepath.Join("old", "Game: Remastered"))
+			record.Set("path", test.path)
+
+			// without a client of the server, since moving has to work while it is offline
+			if _, err := download.NewDownload(record, app, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if path := record.GetString("path"); path != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package download
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Downloads and extractions happen next to the install directory, so the final rename stays on the same filesystem.
+// All files belonging to a download are named with this prefix followed by the download id.
+const stagingPrefix = ".boyl-"
+
+func stagingArchivePath(library, downloadID string) string {
+	return filepath.Join(library, stagingPrefix+downloadID+".tmp")
+}
+
+func stagingDirectory(library, downloadID string) string {
+	return filepath.Join(library, stagingPrefix+downloadID)
+}
+
+func backupDirectory(library, downloadID string) string {
+	return filepath.Join(library, stagingPrefix+downloadID+".old")
+}
+
+// swapDirectories moves the staging directory to the install directory. An existing install is only removed once the new one is in place.
+// The entries named in keep are carried over from the existing install, unless the new one ships them.
+func swapDirectories(logger *slog.Logger, staging, install, backup string, keep ...string) error {
+	if err := recoverBackup(install, backup, keep); err != nil {
+		return err
+	}
+
+	_, err := os.Stat(install)
+	hasInstall := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if hasInstall {
+		if err := os.Rename(install, backup); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(staging, install); err != nil {
+		if hasInstall {
+			return errors.Join(err, os.Rename(backup, install))
+		}
+		return err
+	}
+
+	if hasInstall {
+		if err := carryOver(backup, install, keep); err != nil {
+			return err
+		}
+	}
+
+	// the new install is in place, a backup that is left behind is removed by CleanStaging
+	if err := os.RemoveAll(backup); err != nil {
+		logger.Error("failed to remove previous install", "path", backup, "error", err)
+	}
+	return nil
+}
+
+// recoverBackup handles a backup left behind by an interrupted swap. Without an install, the swap was interrupted
+// before the new install was in place and the backup is the previous install. Otherwise, only the kept entries are taken from it.
+func recoverBackup(install, backup string, keep []string) error {
+	if _, err := os.Lstat(backup); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(install); errors.Is(err, os.ErrNotExist) {
+		return os.Rename(backup, install)
+	} else if err != nil {
+		return err
+	}
+
+	if err := carryOver(backup, install, keep); err != nil {
+		return err
+	}
+	return os.RemoveAll(backup)
+}
+
+// carryOver moves the entries named in keep from one directory to another, unless the other one has them already.
+func carryOver(from, to string, keep []string) error {
+	for _, name := range keep {
+		if _, err := os.Lstat(filepath.Join(to, name)); err == nil {
+			continue
+		}
+		err := os.Rename(filepath.Join(from, name), filepath.Join(to, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// stagingDownloadID returns the download id a staging file belongs to.
+func stagingDownloadID(name string) (string, bool) {
+	if !strings.HasPrefix(name, stagingPrefix) {
+		return "", false
+	}
+	id := strings.TrimPrefix(name, stagingPrefix)
+	id = strings.TrimSuffix(id, ".tmp")
+	id = strings.TrimSuffix(id, ".old")
+	return id, id != ""
+}
@@ -0,0 +1,180 @@
+package download_test
+
+import (
+	"boyl/client/pkg/download"
+	"boyl/client/pkg/settings"
+	"boyl/pkg/testapp"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "boyl/client/migrations"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// writeFiles creates the files with their content below dir, nil creates nothing.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFiles returns the content of the files below dir, or nil if it doesn't exist.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestSwapDirectories(t *testing.T) {
+	tests := []struct {
+		name     string
+		install  map[string]string
+		staging  map[string]string
+		backup   map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "fresh install",
+			staging:  map[string]string{"game.exe": "new"},
+			expected: map[string]string{"game.exe": "new"},
+		},
+		{
+			name:     "reinstall",
+			install:  map[string]string{"game.exe": "old", "old.dll": "old", "prefix/save": "save"},
+			staging:  map[string]string{"game.exe": "new"},
+			expected: map[string]string{"game.exe": "new", "prefix/save": "save"},
+		},
+		{
+			name:     "reinstall shipping the kept entry",
+			install:  map[string]string{"game.exe": "old", "prefix/save": "save"},
+			staging:  map[string]string{"game.exe": "new", "prefix/save": "shipped"},
+			expected: map[string]string{"game.exe": "new", "prefix/save": "shipped"},
+		},
+		{
+			name:     "interrupted before the new install was in place",
+			staging:  map[string]string{"game.exe": "new"},
+			backup:   map[string]string{"game.exe": "old", "prefix/save": "save"},
+			expected: map[string]string{"game.exe": "new", "prefix/save": "save"},
+		},
+		{
+			name:     "interrupted after the new install was in place",
+			install:  map[string]string{"game.exe": "newer"},
+			staging:  map[string]string{"game.exe": "new"},
+			backup:   map[string]string{"game.exe": "old", "prefix/save": "save"},
+			expected: map[string]string{"game.exe": "new", "prefix/save": "save"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			library := t.TempDir()
+			install := filepath.Join(library, "Game")
+			staging := filepath.Join(library, ".boyl-d1")
+			backup := filepath.Join(library, ".boyl-d1.old")
+			writeFiles(t, install, test.install)
+			writeFiles(t, staging, test.staging)
+			writeFiles(t, backup, test.backup)
+
+			if err := download.SwapDirectories(slog.Default(), staging, install, backup, "prefix"); err != nil {
+				t.Fatal(err)
+			}
+
+			if files := readFiles(t, install); !maps.Equal(files, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, files)
+			}
+			for _, path := range []string{staging, backup} {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("expected %s to be removed", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
+
+func TestCleanStaging(t *testing.T) {
+	app := testapp.New(t)
+	downloads, err := app.FindCollectionByNameOrId("downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settingsCollection, err := app.FindCollectionByNameOrId("settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := settings.NewSettings(app, settingsCollection)
+
+	library := t.TempDir()
+	if err := s.Set("libraries", []string{library}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make(map[string]string)
+	for _, status := range []string{"downloading", "extracting", "completed", "failed"} {
+		record := core.NewRecord(downloads)
+		record.Set("game", "g-"+status)
+		record.Set("status", status)
+		if err := app.Save(record); err != nil {
+			t.Fatal(err)
+		}
+		ids[status] = record.Id
+	}
+
+	files := map[string]string{
+		"Game/game.exe":                            "installed",
+		".boyl-" + ids["downloading"] + ".tmp":     "archive",
+		".boyl-" + ids["extracting"] + "/game.exe": "staging",
+		".boyl-" + ids["extracting"] + ".old/save": "backup",
+		".boyl-" + ids["completed"] + ".tmp":       "archive",
+		".boyl-" + ids["completed"] + ".old/save":  "backup",
+		".boyl-" + ids["failed"] + "/game.exe":     "staging",
+		".boyl-unknown0000000.tmp":                 "archive",
+	}
+	writeFiles(t, library, files)
+
+	m := download.NewManager(app, downloads, nil, s, nil)
+	if err := m.CleanStaging(); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the files of downloads that are still in progress are kept, they are needed once the downloads continue
+	expected := map[string]string{
+		"Game/game.exe":                            "installed",
+		".boyl-" + ids["downloading"] + ".tmp":     "archive",
+		".boyl-" + ids["extracting"] + "/game.exe": "staging",
+		".boyl-" + ids["extracting"] + ".old/save": "backup",
+	}
+	if files := readFiles(t, library); !maps.Equal(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
@@ -0,0 +1,124 @@
+package download
+
+import (
+	"boyl/client/pkg/library"
+	"boyl/client/pkg/remote"
+	"boyl/client/pkg/shortcut"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var ErrDownloadInProgress = errors.New("game is being downloaded")
+
+func isInProgress(status string) bool {
+	return status == "starting" || status == "downloading" || status == "extracting" || status == "moving"
+}
+
+func (m *Manager) iconPath(game *core.Record) string {
+	return filepath.Join(m.app.DataDir(), "icons", game.Id+shortcut.IconExtension)
+}
+
+// createShortcuts adds shortcuts starting the game through the launch command, if the createShortcuts setting is enabled.
+// Shortcuts are only created once, so ones the user deleted don't come back when downloads are processed on startup.
+func (m *Manager) createShortcuts(game *core.Record, r *remote.Client, remoteGame *remote.Game) error {
+	if !m.settings.GetBool("createShortcuts") || game.GetString("executable") == "" {
+		return nil
+	}
+	var existing []string
+	game.UnmarshalJSONField("shortcuts", &existing)
+	if len(existing) > 0 {
+		return nil
+	}
+
+	command, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// the executable of an AppImage is inside of a mount that changes on every start
+	if appImage := os.Getenv("APPIMAGE"); appImage != "" {
+		command = appImage
+	}
+	dataDirectory, err := filepath.Abs(m.app.DataDir())
+	if err != nil {
+		return err
+	}
+
+	icon := m.iconPath(game)
+	var cover bytes.Buffer
+	if err := r.Cover(context.Background(), remoteGame, &cover); err != nil {
+		if !errors.Is(err, remote.ErrNoCover) {
+			m.app.Logger().Error("failed to download cover", "game", game.Id, "error", err)
+		}
+		icon = ""
+	} else if err := shortcut.WriteIcon(&cover, icon); err != nil {
+		m.app.Logger().Error("failed to create icon", "game", game.Id, "error", err)
+		icon = ""
+	}
+
+	paths, err := shortcut.Create(shortcut.Shortcut{
+		ID:      game.Id,
+		Name:    remoteGame.Name,
+		Command: command,
+		Args:    []string{"launch", game.Id, "--dir", dataDirectory},
+		Icon:    icon,
+	})
+	if err != nil {
+		return err
+	}
+
+	game.Set("shortcuts", paths)
+	return m.app.Save(game)
+}
+
+// Uninstall removes the files, shortcuts and downloads of an installed game along with the game itself.
+// Snapshots of its saves are kept.
+func (m *Manager) Uninstall(game *core.Record) error {
+	downloads, err := m.app.FindAllRecords(m.downloadsCollection, dbx.HashExp{"game": game.GetString("game")})
+	if err != nil {
+		return err
+	}
+	for _, download := range downloads {
+		if isInProgress(download.GetString("status")) {
+			return ErrDownloadInProgress
+		}
+	}
+
+	path := game.GetString("path")
+	// never delete anything that wasn't installed by the client
+	if path != "" && !library.Contains(m.settings, filepath.Dir(path)) {
+		return fmt.Errorf("%s is not inside of a library", path)
+	}
+
+	var shortcuts []string
+	game.UnmarshalJSONField("shortcuts", &shortcuts)
+	if err := shortcut.Remove(shortcuts); err != nil {
+		return err
+	}
+	if err := os.Remove(m.iconPath(game)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if path != "" {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, download := range downloads {
+		delete(m.downloads, download.Id)
+		if err := m.app.Delete(download); err != nil {
+			return err
+		}
+	}
+
+	return m.app.Delete(game)
+}
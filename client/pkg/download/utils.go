@@ -1,11 +1,6 @@
 package download
 
 import (
-	"io/fs"
-	"math"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 )
 
@@ -48,43 +43,3 @@ func (m *MovingAverage) Get() float64 {
 	}
 	return total / float64(len(m.Values))
 }
-
-func getPathDepth(path string) int {
-	return len(strings.Split(path, string(filepath.Separator)))
-}
-
-func isExecutable(info os.FileInfo) bool {
-	return info.Mode()&0111 != 0 || strings.HasSuffix(info.Name(), ".exe")
-}
-
-// Finds executable in a directory that is closest to the root directory.
-func FindExecutablePath(directory string) (string, error) {
-	var path string
-	depth := math.MaxInt32
-
-	err := filepath.WalkDir(directory, func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
-
-		info, err := d.Info()
-		if err != nil {
-			return err
-		}
-
-		if isExecutable(info) {
-			thisDepth := getPathDepth(p)
-			if thisDepth < depth {
-				path = p
-				depth = thisDepth
-			}
-		}
-
-		return nil
-	})
-
-	return path, err
-}
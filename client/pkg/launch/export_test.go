@@ -0,0 +1,8 @@
+package launch
+
+import "time"
+
+// SetHeartbeat changes how often the duration of running sessions is saved, which has to happen before games are started.
+func (t *Tracker) SetHeartbeat(interval time.Duration) {
+	t.heartbeat = interval
+}
@@ -0,0 +1,53 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// hookTimeout limits how long a hook may take, so a hanging hook doesn't block the game forever.
+const hookTimeout = 5 * time.Minute
+
+// Hooks are shell commands run before a game is started and after it exited, e. g. to mount an image or back up saves.
+// Since they run whatever is stored on the game, only superusers can update the games of the client.
+type Hooks struct {
+	PreLaunch string
+	PostExit  string
+	// Dir is the working directory of the hooks.
+	Dir string
+	// Env is added to the environment of the hooks.
+	Env []string
+}
+
+// runHook runs command through the shell of the operating system.
+func runHook(command, dir string, env []string) error {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if output := strings.TrimSpace(string(output)); output != "" {
+			return fmt.Errorf("hook %q failed: %w: %s", command, err, output)
+		}
+		return fmt.Errorf("hook %q failed: %w", command, err)
+	}
+	return nil
+}
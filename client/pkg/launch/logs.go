@@ -0,0 +1,132 @@
+package launch
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// keepLogs is the number of launch logs kept per game, older ones are removed when a game is started.
+	keepLogs = 5
+	// maxLogSize limits a single log, since some games print for every frame.
+	maxLogSize = 10 << 20
+	// tailSize is how much of the end of a log is read to find the last lines.
+	tailSize = 64 << 10
+)
+
+// logWriter writes the output of a game to a file and drops everything past maxLogSize.
+type logWriter struct {
+	file    *os.File
+	written int64
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if w.written >= maxLogSize {
+		return n, nil
+	}
+	if remaining := maxLogSize - w.written; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	written, err := w.file.Write(p)
+	w.written += int64(written)
+	if err != nil {
+		return written, err
+	}
+	if w.written >= maxLogSize {
+		w.file.WriteString("\n[boyl: log size limit reached, further output is discarded]\n")
+	}
+	// the game must not notice a short write
+	return n, nil
+}
+
+func (w *logWriter) Close() error {
+	return w.file.Close()
+}
+
+// createLog creates a new log for a launch of the game in directory and removes the oldest logs.
+func createLog(directory, game string) (*logWriter, string, error) {
+	directory = filepath.Join(directory, game)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, "", err
+	}
+
+	path := filepath.Join(directory, time.Now().Format("20060102-150405.000")+".log")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := rotateLogs(directory, keepLogs); err != nil {
+		file.Close()
+		return nil, "", err
+	}
+
+	return &logWriter{file: file}, path, nil
+}
+
+func rotateLogs(directory string, keep int) error {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+
+	var logs []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
+			logs = append(logs, entry.Name())
+		}
+	}
+	// the names are timestamps, so the oldest come first
+	slices.Sort(logs)
+
+	for len(logs) > keep {
+		if err := os.Remove(filepath.Join(directory, logs[0])); err != nil {
+			return err
+		}
+		logs = logs[1:]
+	}
+	return nil
+}
+
+// TailLog returns up to n of the last lines of a log.
+func TailLog(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := max(info.Size()-tailSize, 0)
+	data, err := io.ReadAll(io.NewSectionReader(file, offset, info.Size()-offset))
+	if err != nil {
+		return nil, err
+	}
+	// the first line is most likely cut off
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) == 0 {
+		return []string{}, nil
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
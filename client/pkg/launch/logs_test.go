@@ -0,0 +1,48 @@
+package launch_test
+
+import (
+	"boyl/client/pkg/launch"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTailLog(t *testing.T) {
+	var long strings.Builder
+	for i := range 10000 {
+		fmt.Fprintf(&long, "line %d\n", i)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		n        int
+		expected []string
+	}{
+		{"empty", "", 10, []string{}},
+		{"short", "a\nb\nc\n", 10, []string{"a", "b", "c"}},
+		{"last lines", "a\nb\nc", 2, []string{"b", "c"}},
+		{"windows line endings", "a\r\nb\r\n", 10, []string{"a", "b"}},
+		{"longer than tail", long.String(), 3, []string{"line 9997", "line 9998", "line 9999"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "game.log")
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			lines, err := launch.TailLog(path, test.n)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(lines, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package launch
+
+import (
+	"boyl/client/pkg/runner"
+	"boyl/client/pkg/settings"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Runners returns the detected runners followed by the ones added in the settings.
+func Runners(s *settings.Settings) []runner.Runner {
+	var custom []runner.Runner
+	s.Unmarshal("runners", &custom)
+	return runner.Detect(custom)
+}
+
+// GameOptions resolves the options to start profile of a client game, falling back to the defaults from the settings.
+// The hooks get the game and profile passed as BOYL_* environment variables.
+// Windows executables are started through the default runner, unless the game picks one.
+func GameOptions(game *core.Record, s *settings.Settings, profile Profile) (Options, error) {
+	path := game.GetString("path")
+	options := Options{
+		Launcher:         game.GetString("launcher"),
+		DLLOverrides:     game.GetString("dllOverrides"),
+		WorkingDirectory: game.GetString("workingDirectory"),
+	}
+	if options.Launcher == "" {
+		options.Launcher = s.GetString("defaultLauncher")
+	}
+	// relative to the install directory, so it survives moving the game
+	if options.WorkingDirectory != "" && !filepath.IsAbs(options.WorkingDirectory) {
+		options.WorkingDirectory = filepath.Join(path, options.WorkingDirectory)
+	}
+	game.UnmarshalJSONField("env", &options.Env)
+
+	options.Hooks = Hooks{
+		PreLaunch: game.GetString("preLaunch"),
+		PostExit:  game.GetString("postExit"),
+		Dir:       path,
+		Env: []string{
+			"BOYL_GAME=" + game.GetString("game"),
+			"BOYL_GAME_PATH=" + path,
+			"BOYL_EXECUTABLE=" + profile.Executable,
+			"BOYL_PROFILE=" + profile.Name,
+		},
+	}
+	for key, value := range options.Env {
+		options.Hooks.Env = append(options.Hooks.Env, key+"="+value)
+	}
+
+	runnerPath := game.GetString("runner")
+	if runnerPath == "" && runner.NeedsRunner(runtime.GOOS, profile.Executable) {
+		runnerPath = s.GetString("defaultRunner")
+	}
+	if runnerPath == "" {
+		return options, nil
+	}
+
+	r, err := runner.Find(Runners(s), runnerPath)
+	if err != nil {
+		return options, err
+	}
+	options.Runner = r
+	options.Prefix = runner.PrefixPath(path)
+
+	return options, nil
+}
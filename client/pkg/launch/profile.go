@@ -0,0 +1,119 @@
+package launch
+
+import (
+	"boyl/client/pkg/runner"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/google/shlex"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// MainProfile is the name of the profile built from the executable and args of a game.
+const MainProfile = "main"
+
+// Profile is a way to start a game, e. g. the game itself, its config tool or a dedicated server.
+type Profile struct {
+	Name       string `json:"name"`
+	Executable string `json:"executable"`
+	Args       string `json:"args"`
+}
+
+// Profiles returns the launch profiles of a client game, the main profile first.
+func Profiles(game *core.Record) []Profile {
+	profiles := []Profile{{
+		Name:       MainProfile,
+		Executable: game.GetString("executable"),
+		Args:       game.GetString("args"),
+	}}
+
+	var extra []Profile
+	if err := game.UnmarshalJSONField("profiles", &extra); err == nil {
+		profiles = append(profiles, extra...)
+	}
+	return profiles
+}
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// FindProfile returns the profile with the given name. An empty name selects the main profile.
+func FindProfile(game *core.Record, name string) (Profile, error) {
+	if name == "" {
+		name = MainProfile
+	}
+	for _, profile := range Profiles(game) {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+}
+
+// Options configure how a profile is started.
+type Options struct {
+	// Launcher wraps the command, e. g. "gamemoderun" or "mangohud".
+	Launcher string
+	// Runner starts windows executables, nil starts the executable directly.
+	Runner *runner.Runner
+	// Prefix is passed to the runner.
+	Prefix       string
+	DLLOverrides string
+	Env          map[string]string
+	// WorkingDirectory defaults to the directory of the executable.
+	WorkingDirectory string
+	Hooks            Hooks
+}
+
+// Command builds the command starting the profile, through the runner and launcher if they are set.
+func Command(profile Profile, options Options) (*exec.Cmd, error) {
+	if profile.Executable == "" {
+		return nil, fmt.Errorf("profile %s has no executable", profile.Name)
+	}
+
+	userArgs, err := shlex.Split(profile.Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse args: %w", err)
+	}
+
+	launcherArgs, err := shlex.Split(options.Launcher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse launcher: %w", err)
+	}
+
+	name := profile.Executable
+	args := userArgs
+	env := os.Environ()
+
+	if options.Runner != nil {
+		var runnerEnv []string
+		name, args, runnerEnv, err = options.Runner.Command(profile.Executable, userArgs, runner.Options{
+			Prefix:       options.Prefix,
+			DLLOverrides: options.DLLOverrides,
+			Env:          options.Env,
+		})
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, runnerEnv...)
+	} else {
+		for key, value := range options.Env {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	if len(launcherArgs) > 0 {
+		args = append(append(launcherArgs[1:], name), args...)
+		name = launcherArgs[0]
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = env
+	cmd.Dir = options.WorkingDirectory
+	if cmd.Dir == "" {
+		cmd.Dir = filepath.Dir(profile.Executable)
+	}
+	return cmd, nil
+}
@@ -0,0 +1,76 @@
+package launch_test
+
+import (
+	"boyl/client/pkg/launch"
+	"boyl/client/pkg/runner"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	executable := filepath.Join("games", "Game", "bin", "game.exe")
+	wine := &runner.Runner{Name: "wine", Kind: runner.KindWine, Path: "/usr/bin/wine"}
+
+	tests := []struct {
+		name    string
+		options launch.Options
+		args    []string
+		dir     string
+		env     string
+	}{
+		{
+			name: "direct",
+			options: launch.Options{
+				Env: map[string]string{"FOO": "bar"},
+			},
+			args: []string{executable, "-windowed"},
+			dir:  filepath.Dir(executable),
+			env:  "FOO=bar",
+		},
+		{
+			name: "launcher",
+			options: launch.Options{
+				Launcher:         "gamemoderun --flag",
+				WorkingDirectory: "games",
+			},
+			args: []string{"gamemoderun", "--flag", executable, "-windowed"},
+			dir:  "games",
+		},
+		{
+			name: "launcher and runner",
+			options: launch.Options{
+				Launcher: "mangohud",
+				Runner:   wine,
+				Env:      map[string]string{"DXVK_HUD": "fps"},
+			},
+			args: []string{"mangohud", "/usr/bin/wine", executable, "-windowed"},
+			dir:  filepath.Dir(executable),
+			env:  "DXVK_HUD=fps",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := launch.Command(launch.Profile{Name: "main", Executable: executable, Args: "-windowed"}, test.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(cmd.Args, test.args) {
+				t.Errorf("expected args %q, got %q", test.args, cmd.Args)
+			}
+			if cmd.Dir != test.dir {
+				t.Errorf("expected dir %q, got %q", test.dir, cmd.Dir)
+			}
+			if test.env != "" && !slices.Contains(cmd.Env, test.env) {
+				t.Errorf("expected env to contain %q", test.env)
+			}
+		})
+	}
+}
+
+func TestCommandWithoutExecutable(t *testing.T) {
+	if _, err := launch.Command(launch.Profile{Name: "main"}, launch.Options{}); err == nil {
+		t.Error("expected an error for a profile without executable")
+	}
+}
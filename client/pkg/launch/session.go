@@ -0,0 +1,330 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+var ErrAlreadyRunning = errors.New("game is already running")
+
+const (
+	// heartbeatInterval is how often the duration of a running session is saved, so a session survives the client being killed.
+	heartbeatInterval = time.Minute
+	// staleAfter is how long a running session can go without a heartbeat before it counts as ended, e. g. because the
+	// client was killed.
+	staleAfter = 3 * heartbeatInterval
+	// quickExit is how long a game has to run for a non-zero exit code to not count as a crash on startup.
+	quickExit = 10 * time.Second
+	// waitDelay is how long to wait for the output of child processes after a game exited, e. g. the wineserver.
+	waitDelay = 5 * time.Second
+)
+
+// Tracker starts games and records their play sessions in the sessions collection. Games started by another process,
+// e. g. by the launch command of a shortcut while the client isn't running, are recognized by their running sessions.
+type Tracker struct {
+	app          core.App
+	collection   *core.Collection
+	logDirectory string
+	heartbeat    time.Duration
+
+	mu      sync.Mutex
+	running map[string]*exec.Cmd
+	// exited is closed when a game is no longer running
+	exited map[string]chan struct{}
+	syncMu sync.Mutex
+	onExit []func(session *core.Record)
+}
+
+// NewTracker returns a Tracker, which writes the output of every launch into a log in logDirectory.
+func NewTracker(app core.App, collection *core.Collection, logDirectory string) *Tracker {
+	return &Tracker{
+		app:          app,
+		collection:   collection,
+		logDirectory: logDirectory,
+		heartbeat:    heartbeatInterval,
+		mu:           sync.Mutex{},
+		running:      make(map[string]*exec.Cmd),
+		exited:       make(map[string]chan struct{}),
+	}
+}
+
+// Start starts the command of a game and records a session until it exits. game is the id of the game on the server,
+// which is the id of a server profile. The pre-launch hook runs before the command is started and must succeed, the post-exit hook runs after the game exited.
+func (t *Tracker) Start(game, server, profile string, cmd *exec.Cmd, hooks Hooks) (*core.Record, error) {
+	t.mu.Lock()
+	if _, ok := t.running[game]; ok {
+		t.mu.Unlock()
+		return nil, ErrAlreadyRunning
+	}
+	if elsewhere, err := t.runningElsewhere(game); err != nil || elsewhere {
+		t.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadyRunning
+	}
+	// reserved while the pre-launch hook runs, so the game can't be started twice
+	t.running[game] = nil
+	t.exited[game] = make(chan struct{})
+	t.mu.Unlock()
+
+	session, err := t.start(game, server, profile, cmd, hooks)
+	if err != nil {
+		t.release(game)
+		return nil, err
+	}
+	return session, nil
+}
+
+// runningElsewhere reports whether another process runs a game, whose session is still kept alive by its heartbeat.
+func (t *Tracker) runningElsewhere(game string) (bool, error) {
+	sessions, err := t.app.FindAllRecords(t.collection, dbx.HashExp{"game": game, "running": true})
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	return slices.ContainsFunc(sessions, func(session *core.Record) bool {
+		return alive(session, now)
+	}), nil
+}
+
+// alive reports whether a session is running and its heartbeat was saved recently.
+func alive(session *core.Record, now time.Time) bool {
+	return session.GetBool("running") && now.Sub(session.GetDateTime("updated").Time()) < staleAfter
+}
+
+func (t *Tracker) release(game string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	close(t.exited[game])
+	delete(t.exited, game)
+	delete(t.running, game)
+}
+
+func (t *Tracker) start(game, server, profile string, cmd *exec.Cmd, hooks Hooks) (*core.Record, error) {
+	if err := runHook(hooks.PreLaunch, hooks.Dir, hooks.Env); err != nil {
+		return nil, err
+	}
+
+	log, logPath, err := createLog(t.logDirectory, game)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(log, "[boyl] starting %q in %s\n", cmd.Args, cmd.Dir)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.WaitDelay = waitDelay
+
+	start := time.Now()
+	session := core.NewRecord(t.collection)
+	session.Set("game", game)
+	session.Set("server", server)
+	session.Set("profile", profile)
+	session.Set("start", start)
+	session.Set("running", true)
+	session.Set("log", logPath)
+	if err := t.app.Save(session); err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(log, "[boyl] failed to start: %v\n", err)
+		log.Close()
+		if err := t.app.Delete(session); err != nil {
+			t.app.Logger().Error("failed to delete session", "error", err)
+		}
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.running[game] = cmd
+	t.mu.Unlock()
+
+	go t.wait(game, session, cmd, log, start, hooks)
+
+	return session, nil
+}
+
+func (t *Tracker) wait(game string, session *core.Record, cmd *exec.Cmd, log *logWriter, start time.Time, hooks Hooks) {
+	done := make(chan struct{})
+	// closed once the heartbeat returned, so it can't save the session at the same time as the final save below
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(t.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				session.Set("duration", time.Since(start).Seconds())
+				if err := t.app.Save(session); err != nil {
+					t.app.Logger().Error("failed to save session", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+	<-stopped
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil && exitCode == -1 {
+		t.app.Logger().Error("failed to wait for game", "game", game, "error", err)
+	}
+
+	end := time.Now()
+	duration := end.Sub(start)
+	fmt.Fprintf(log, "[boyl] exited with code %d after %s\n", exitCode, duration.Round(time.Second))
+	if err := log.Close(); err != nil {
+		t.app.Logger().Error("failed to close launch log", "game", game, "error", err)
+	}
+
+	session.Set("end", end)
+	session.Set("duration", duration.Seconds())
+	session.Set("exitCode", exitCode)
+	session.Set("running", false)
+	// most likely a missing dependency or a broken prefix, rather than the player quitting
+	session.Set("crashed", exitCode != 0 && duration < quickExit)
+	if err := t.app.Save(session); err != nil {
+		t.app.Logger().Error("failed to save session", "error", err)
+	}
+
+	// the game counts as running until the hook is done, e. g. an image is unmounted
+	env := slices.Concat(hooks.Env, []string{fmt.Sprintf("BOYL_EXIT_CODE=%d", exitCode)})
+	if err := runHook(hooks.PostExit, hooks.Dir, env); err != nil {
+		t.app.Logger().Error("post-exit hook failed", "game", game, "error", err)
+	}
+	for _, fn := range t.onExit {
+		fn(session)
+	}
+
+	t.release(game)
+}
+
+// OnExit registers a function that is called with the finished session after a game and its post-exit hook exited.
+// The game still counts as running while the functions are called. It must not be called after games have been started.
+func (t *Tracker) OnExit(fn func(session *core.Record)) {
+	t.onExit = append(t.onExit, fn)
+}
+
+// Wait blocks until a game started by the tracker exited and the functions registered with OnExit returned.
+func (t *Tracker) Wait(game string) {
+	t.mu.Lock()
+	exited, ok := t.exited[game]
+	t.mu.Unlock()
+
+	if ok {
+		<-exited
+	}
+}
+
+// IsRunning reports whether a game started by the tracker is still running.
+func (t *Tracker) IsRunning(game string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.running[game]
+	return ok
+}
+
+// CloseStale ends sessions that were still running when the client exited, using the last saved duration.
+// Sessions whose heartbeat is still saved by another process are kept.
+func (t *Tracker) CloseStale() error {
+	sessions, err := t.app.FindAllRecords(t.collection)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, session := range sessions {
+		if !session.GetBool("running") || alive(session, now) {
+			continue
+		}
+
+		duration := time.Duration(session.GetFloat("duration") * float64(time.Second))
+		session.Set("end", session.GetDateTime("start").Time().Add(duration))
+		session.Set("exitCode", -1)
+		session.Set("running", false)
+		if err := t.app.Save(session); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LastSession returns the latest session of a game.
+func (t *Tracker) LastSession(game string) (*core.Record, error) {
+	sessions, err := t.app.FindRecordsByFilter(t.collection, "game = {:game}", "-start", 1, 0, dbx.Params{"game": game})
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("game %s has no sessions", game)
+	}
+	return sessions[0], nil
+}
+
+// Playtime sums up the sessions of a game. Durations are in seconds.
+type Playtime struct {
+	Game       string         `json:"game"`
+	Total      float64        `json:"total"`
+	Recent     float64        `json:"recent"`
+	Sessions   int            `json:"sessions"`
+	LastPlayed types.DateTime `json:"lastPlayed"`
+	Running    bool           `json:"running"`
+}
+
+// Playtime returns the playtime of every game that has been played. Sessions started after since count as recent.
+func (t *Tracker) Playtime(since time.Time) ([]*Playtime, error) {
+	sessions, err := t.app.FindRecordsByFilter(t.collection, "", "start", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var result []*Playtime
+	byGame := make(map[string]*Playtime)
+	for _, session := range sessions {
+		game := session.GetString("game")
+		playtime, ok := byGame[game]
+		if !ok {
+			playtime = &Playtime{Game: game, Running: t.IsRunning(game)}
+			byGame[game] = playtime
+			result = append(result, playtime)
+		}
+
+		duration := session.GetFloat("duration")
+		start := session.GetDateTime("start")
+		// sessions that stopped their heartbeat are ended by CloseStale later on, with the duration saved last
+		if alive(session, now) {
+			duration = now.Sub(start.Time()).Seconds()
+			playtime.Running = true
+		}
+
+		playtime.Total += duration
+		if start.Time().After(since) {
+			playtime.Recent += duration
+		}
+		playtime.Sessions++
+		playtime.LastPlayed = start
+	}
+
+	return result, nil
+}
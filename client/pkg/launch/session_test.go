@@ -0,0 +1,240 @@
+package launch_test
+
+import (
+	"boyl/client/pkg/launch"
+	"boyl/pkg/testapp"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "boyl/client/migrations"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestMain lets the test binary play a game, which runs until its stdin is closed and exits with BOYL_TEST_GAME_EXIT.
+func TestMain(m *testing.M) {
+	if code := os.Getenv("BOYL_TEST_GAME_EXIT"); code != "" {
+		io.Copy(io.Discard, os.Stdin)
+		exitCode, _ := strconv.Atoi(code)
+		os.Exit(exitCode)
+	}
+	os.Exit(m.Run())
+}
+
+// game returns the command of a game and a function that quits it.
+func game(t *testing.T, exitCode int) (*exec.Cmd, func()) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		r.Close()
+		w.Close()
+	})
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "BOYL_TEST_GAME_EXIT="+strconv.Itoa(exitCode))
+	cmd.Stdin = r
+	return cmd, func() { w.Close() }
+}
+
+func setup(t *testing.T) (core.App, *core.Collection, *launch.Tracker) {
+	t.Helper()
+	app := testapp.New(t)
+	collection, err := app.FindCollectionByNameOrId("sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, collection, launch.NewTracker(app, collection, t.TempDir())
+}
+
+func TestTracker(t *testing.T) {
+	app, collection, tracker := setup(t)
+	tracker.SetHeartbeat(10 * time.Millisecond)
+	var exited []*core.Record
+	tracker.OnExit(func(session *core.Record) { exited = append(exited, session) })
+
+	cmd, quit := game(t, 3)
+	session, err := tracker.Start("g1", "s1", "Main", cmd, launch.Hooks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.GetBool("running") || session.GetDateTime("start").IsZero() || session.GetString("server") != "s1" {
+		t.Errorf("expected a running session, got %v", session)
+	}
+	if !tracker.IsRunning("g1") {
+		t.Error("expected the game to be running")
+	}
+
+	again, _ := game(t, 0)
+	if _, err := tracker.Start("g1", "s1", "Main", again, launch.Hooks{}); !errors.Is(err, launch.ErrAlreadyRunning) {
+		t.Errorf("expected ErrAlreadyRunning, got %v", err)
+	}
+
+	// the heartbeat saves the duration while the game runs
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		saved, err := app.FindRecordById(collection, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.GetFloat("duration") > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the heartbeat to save the duration")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	quit()
+	tracker.Wait("g1")
+	if tracker.IsRunning("g1") {
+		t.Error("expected the game to have exited")
+	}
+	if len(exited) != 1 {
+		t.Fatalf("expected the exit to be reported once, got %d", len(exited))
+	}
+
+	saved, err := app.FindRecordById(collection, session.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.GetBool("running") || saved.GetDateTime("end").IsZero() || saved.GetFloat("duration") <= 0 {
+		t.Errorf("expected an ended session, got %v", saved)
+	}
+	if saved.GetInt("exitCode") != 3 || !saved.GetBool("crashed") {
+		t.Errorf("expected exit code 3 right after the start to count as a crash, got %d", saved.GetInt("exitCode"))
+	}
+}
+
+// runningSession saves a session of another process, whose heartbeat was saved age ago.
+func runningSession(t *testing.T, app core.App, collection *core.Collection, game string, age time.Duration) *core.Record {
+	t.Helper()
+	session := core.NewRecord(collection)
+	session.Set("game", game)
+	session.Set("start", time.Now().Add(-time.Hour))
+	session.Set("duration", 600)
+	session.Set("running", true)
+	if err := app.Save(session); err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Now().Add(-age).UTC().Format("2006-01-02 15:04:05.000Z")
+	if _, err := app.DB().Update(collection.Name, dbx.Params{"updated": updated}, dbx.HashExp{"id": session.Id}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestRunningElsewhere(t *testing.T) {
+	app, collection, tracker := setup(t)
+	alive := runningSession(t, app, collection, "g1", 0)
+	stale := runningSession(t, app, collection, "g2", time.Hour)
+
+	cmd, _ := game(t, 0)
+	if _, err := tracker.Start("g1", "", "Main", cmd, launch.Hooks{}); !errors.Is(err, launch.ErrAlreadyRunning) {
+		t.Errorf("expected a game running in another process to be rejected, got %v", err)
+	}
+
+	if err := tracker.CloseStale(); err != nil {
+		t.Fatal(err)
+	}
+	alive, err := app.FindRecordById(collection, alive.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !alive.GetBool("running") {
+		t.Error("expected the session of another process to be kept")
+	}
+	stale, err = app.FindRecordById(collection, stale.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := stale.GetDateTime("start").Time().Add(10 * time.Minute)
+	if stale.GetBool("running") || stale.GetInt("exitCode") != -1 || !stale.GetDateTime("end").Time().Equal(end) {
+		t.Errorf("expected the stale session to end after its last saved duration, got %v", stale)
+	}
+
+	// the game can be started again once its session ended
+	cmd, quit := game(t, 0)
+	if _, err := tracker.Start("g2", "", "Main", cmd, launch.Hooks{}); err != nil {
+		t.Fatal(err)
+	}
+	quit()
+	tracker.Wait("g2")
+}
+
+func TestPlaytime(t *testing.T) {
+	app, collection, tracker := setup(t)
+	now := time.Now()
+
+	for _, s := range []struct {
+		game     string
+		start    time.Time
+		duration float64
+	}{
+		{"g1", now.AddDate(0, -2, 0), 3600},
+		{"g1", now.AddDate(0, 0, -1), 600},
+		{"g2", now.AddDate(0, 0, -3), 120},
+	} {
+		session := core.NewRecord(collection)
+		session.Set("game", s.game)
+		session.Set("start", s.start)
+		session.Set("duration", s.duration)
+		if err := app.Save(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runningSession(t, app, collection, "g3", 0)
+	// killed without ending its session, it counts with the duration saved last
+	runningSession(t, app, collection, "g2", time.Hour)
+
+	playtimes, err := tracker.Playtime(now.AddDate(0, 0, -14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byGame := make(map[string]*launch.Playtime)
+	for _, playtime := range playtimes {
+		byGame[playtime.Game] = playtime
+	}
+
+	tests := []struct {
+		game     string
+		total    float64
+		recent   float64
+		sessions int
+		running  bool
+	}{
+		{"g1", 4200, 600, 2, false},
+		{"g2", 720, 720, 2, false},
+		{"g3", 3600, 3600, 1, true},
+	}
+	for _, test := range tests {
+		t.Run(test.game, func(t *testing.T) {
+			playtime, ok := byGame[test.game]
+			if !ok {
+				t.Fatal("expected the game to have a playtime")
+			}
+			// the running session is measured until now
+			if playtime.Total < test.total || playtime.Total > test.total+60 {
+				t.Errorf("expected a total of %v, got %v", test.total, playtime.Total)
+			}
+			if playtime.Recent < test.recent || playtime.Recent > test.recent+60 {
+				t.Errorf("expected %v recently, got %v", test.recent, playtime.Recent)
+			}
+			if playtime.Sessions != test.sessions || playtime.Running != test.running {
+				t.Errorf("expected %d sessions and running %v, got %d and %v", test.sessions, test.running, playtime.Sessions, playtime.Running)
+			}
+		})
+	}
+	if lastPlayed := byGame["g1"].LastPlayed.Time(); !lastPlayed.Equal(now.AddDate(0, 0, -1).Truncate(time.Millisecond)) {
+		t.Errorf("expected the last session to be the last played, got %v", lastPlayed)
+	}
+}
@@ -0,0 +1,45 @@
+package launch
+
+import (
+	"boyl/client/pkg/remote"
+	"context"
+
+	"github.com/pocketbase/dbx"
+)
+
+// Sync sends the ended sessions of games from a server profile that haven't been synced yet to its server.
+func (t *Tracker) Sync(ctx context.Context, r *remote.Client, server string) error {
+	t.syncMu.Lock()
+	defer t.syncMu.Unlock()
+
+	records, err := t.app.FindAllRecords(t.collection, dbx.HashExp{"running": false, "synced": false, "server": server})
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	sessions := make([]remote.Session, 0, len(records))
+	for _, record := range records {
+		sessions = append(sessions, remote.Session{
+			ID:       record.Id,
+			Game:     record.GetString("game"),
+			Start:    record.GetDateTime("start").Time(),
+			Duration: record.GetFloat("duration"),
+		})
+	}
+
+	if _, err := r.SyncPlaytime(ctx, sessions); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		record.Set("synced", true)
+		if err := t.app.Save(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package library
+
+import (
+	"boyl/client/pkg/settings"
+	"path/filepath"
+	"slices"
+)
+
+// Library is a folder games are installed to.
+type Library struct {
+	Path  string `json:"path"`
+	Free  uint64 `json:"free"`
+	Total uint64 `json:"total"`
+	// Error is set when the disk usage could not be read, e.g. because the drive is not mounted.
+	Error string `json:"error,omitempty"`
+}
+
+// Paths returns the configured library folders, the first one being the default.
+// The gamesDirectory setting is used when no libraries are configured.
+func Paths(s *settings.Settings) []string {
+	paths := s.GetStrings("libraries")
+	if len(paths) == 0 {
+		if gamesDirectory := s.GetString("gamesDirectory"); gamesDirectory != "" {
+			paths = []string{gamesDirectory}
+		}
+	}
+	return paths
+}
+
+// Default returns the library new games are installed to if none is chosen.
+func Default(s *settings.Settings) string {
+	paths := Paths(s)
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[0]
+}
+
+// Contains reports whether path is a configured library.
+func Contains(s *settings.Settings, path string) bool {
+	return slices.ContainsFunc(Paths(s), func(p string) bool {
+		return filepath.Clean(p) == filepath.Clean(path)
+	})
+}
+
+// List returns the configured libraries along with their disk usage.
+func List(s *settings.Settings) []Library {
+	paths := Paths(s)
+	libraries := make([]Library, 0, len(paths))
+	for _, path := range paths {
+		library := Library{Path: path}
+		free, total, err := DiskUsage(path)
+		if err != nil {
+			library.Error = err.Error()
+		} else {
+			library.Free = free
+			library.Total = total
+		}
+		libraries = append(libraries, library)
+	}
+	return libraries
+}
@@ -0,0 +1,40 @@
+package library
+
+import (
+	"strings"
+	"unicode"
+)
+
+// reservedNames can not be used as file names on windows, regardless of the extension.
+var reservedNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
+
+// FolderName turns a game name into a folder name that is valid on every supported os.
+// Characters windows does not allow are replaced with an underscore.
+func FolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// windows strips trailing dots and spaces, which would make the folder unreachable
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "_"
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	for _, reserved := range reservedNames {
+		if strings.EqualFold(strings.TrimSpace(base), reserved) {
+			return "_" + name
+		}
+	}
+
+	return name
+}
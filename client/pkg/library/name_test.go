@@ -0,0 +1,37 @@
+package library_test
+
+import (
+	"boyl/client/pkg/library"
+	"testing"
+)
+
+func TestFolderName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"Half-Life 2", "Half-Life 2"},
+		{"S.T.A.L.K.E.R.: Shadow of Chernobyl", "S.T.A.L.K.E.R._ Shadow of Chernobyl"},
+		{"What/If?", "What_If_"},
+		{`<>:"/\|?*`, "_________"},
+		{"Game\x00Name\t", "Game_Name_"},
+		{"Trailing...", "Trailing"},
+		{"  Spaces  ", "Spaces"},
+		{"CON", "_CON"},
+		{"con.txt", "_con.txt"},
+		{"Com1", "_Com1"},
+		{"Console", "Console"},
+		{"", "_"},
+		{"...", "_"},
+		{"Ōkami", "Ōkami"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := library.FolderName(test.name)
+			if got != test.expected {
+				t.Errorf("FolderName(%q) = %q, want %q", test.name, got, test.expected)
+			}
+		})
+	}
+}
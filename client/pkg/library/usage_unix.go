@@ -0,0 +1,14 @@
+//go:build !windows
+
+package library
+
+import "golang.org/x/sys/unix"
+
+// DiskUsage returns the bytes available to the user and the total size of the filesystem containing path.
+func DiskUsage(path string) (free, total uint64, err error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
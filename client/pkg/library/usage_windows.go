@@ -0,0 +1,15 @@
+package library
+
+import "golang.org/x/sys/windows"
+
+// DiskUsage returns the bytes available to the user and the total size of the volume containing path.
+func DiskUsage(path string) (free, total uint64, err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, nil); err != nil {
+		return 0, 0, err
+	}
+	return free, total, nil
+}
@@ -0,0 +1,326 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type AuthRequest struct {
+	Identity string `json:"identity"`
+	Password string `json:"password"`
+}
+type RegisterRequest struct {
+	Invite   string `json:"invite"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+type AuthResponse struct {
+	Token  string `json:"token"`
+	Record struct {
+		Email string `json:"email"`
+	} `json:"record"`
+}
+
+// authTransport adds the token of the user to requests. The token is renewed once half of its lifetime passed,
+// and requests rejected with 401 are sent again after logging in with the password, if it is known.
+type authTransport struct {
+	inner  http.RoundTripper
+	remote *Client
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.remote.refreshIfDue(req.Context())
+
+	token := t.remote.Token()
+	res, err := t.inner.RoundTrip(withToken(req, token))
+	if err != nil || res.StatusCode != http.StatusUnauthorized || token == "" {
+		return res, err
+	}
+	// streamed bodies, e. g. save uploads, can't be sent twice
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return res, nil
+	}
+	if err := t.remote.reauthenticate(req.Context(), token); err != nil {
+		return res, nil
+	}
+
+	retry := withToken(req, t.remote.Token())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+		retry.Body = body
+	}
+	res.Body.Close()
+	return t.inner.RoundTrip(retry)
+}
+
+func withToken(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// Token returns the current token of the user, empty if the client isn't logged in.
+func (r *Client) Token() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// OnToken registers fn to be called whenever the token changes, so it can be stored. An empty token means the user logged out.
+// fn must not call methods of the client.
+func (r *Client) OnToken(fn func(token string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onToken = fn
+}
+
+// Authenticate logs in with the password of the user. The password is kept in memory to log in again if the server rejects the token later on.
+// It fails with ErrVersionMismatch if the client can't talk to the server, see Info.
+func (r *Client) Authenticate(ctx context.Context, email, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.connect(ctx); err != nil {
+		return err
+	}
+	return r.login(ctx, email, password)
+}
+
+// Register creates an account with an invite of the server and logs into it, like Authenticate.
+// Invalid invites and passwords are rejected with an Error with status 400, whose message says why.
+func (r *Client) Register(ctx context.Context, invite, email, password string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.connect(ctx); err != nil {
+		return err
+	}
+
+	res, err := r.authRequest(ctx, "/api/register", "", RegisterRequest{
+		Invite:   invite,
+		Email:    email,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+	r.identity = email
+	r.password = password
+	r.setToken(res.Token)
+	return nil
+}
+
+// Resume continues a session with a token stored earlier. The token is renewed right away, which also checks it is still valid.
+// If the server can't be reached, the token is kept and renewed with a later request. It is kept as well if the client
+// can't talk to the server, which fails with ErrVersionMismatch until one of them is updated.
+func (r *Client) Resume(ctx context.Context, email, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identity = email
+	r.token = token
+	r.password = ""
+	err := r.connect(ctx)
+	if err == nil {
+		err = r.refresh(ctx)
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		r.clear()
+	} else if err != nil {
+		r.refreshAt = time.Now().Add(time.Minute)
+	}
+	return err
+}
+
+// Logout forgets the token and password of the user.
+func (r *Client) Logout() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clear()
+}
+
+func (r *Client) clear() {
+	r.identity = ""
+	r.password = ""
+	r.setToken("")
+}
+
+// refreshIfDue renews the token without holding the lock during the request, so a slow server doesn't hold up other
+// requests, which go out with the current token in the meantime. Only one renewal is sent at a time.
+func (r *Client) refreshIfDue(ctx context.Context) {
+	r.mu.Lock()
+	token := r.token
+	if token == "" || r.refreshing || time.Now().Before(r.refreshAt) {
+		r.mu.Unlock()
+		return
+	}
+	r.refreshing = true
+	r.mu.Unlock()
+
+	res, err := r.authRequest(ctx, "/api/collections/users/auth-refresh", token, nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshing = false
+	// the user logged out or in again in the meantime
+	if r.token != token {
+		return
+	}
+	// requests still go out with the current token, a rejected one leads to logging in again
+	if err != nil {
+		r.refreshAt = time.Now().Add(time.Minute)
+		return
+	}
+	r.renewed(res)
+}
+
+// reauthenticate logs in again after the server rejected token. Like refreshIfDue, the lock isn't held during the request.
+func (r *Client) reauthenticate(ctx context.Context, token string) error {
+	r.mu.Lock()
+	identity, password := r.identity, r.password
+	// a concurrent request already got a new token
+	if r.token != token {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+	if password == "" {
+		return ErrUnauthorized
+	}
+
+	res, err := r.passwordAuth(ctx, identity, password)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the user logged out or in again in the meantime
+	if r.token != token {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.setToken(res.Token)
+	return nil
+}
+
+func (r *Client) login(ctx context.Context, email, password string) error {
+	res, err := r.passwordAuth(ctx, email, password)
+	if err != nil {
+		return err
+	}
+
+	r.identity = email
+	r.password = password
+	r.setToken(res.Token)
+	return nil
+}
+
+func (r *Client) passwordAuth(ctx context.Context, email, password string) (*AuthResponse, error) {
+	res, err := r.authRequest(ctx, "/api/collections/users/auth-with-password", "", AuthRequest{
+		Identity: email,
+		Password: password,
+	})
+	// PocketBase rejects wrong passwords as a bad request
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	return res, err
+}
+
+func (r *Client) refresh(ctx context.Context) error {
+	res, err := r.authRequest(ctx, "/api/collections/users/auth-refresh", r.token, nil)
+	if err != nil {
+		return err
+	}
+	r.renewed(res)
+	return nil
+}
+
+func (r *Client) renewed(res *AuthResponse) {
+	if res.Record.Email != "" {
+		r.identity = res.Record.Email
+	}
+	r.setToken(res.Token)
+}
+
+func (r *Client) setToken(token string) {
+	r.token = token
+	r.refreshAt = time.Time{}
+	if expiry := tokenExpiry(token); !expiry.IsZero() {
+		r.refreshAt = time.Now().Add(time.Until(expiry) / 2)
+	}
+	if r.onToken != nil {
+		r.onToken(token)
+	}
+}
+
+// authRequest is sent without the auth transport, which would otherwise try to renew the token it is renewing.
+func (r *Client) authRequest(ctx context.Context, path, token string, body any) (*AuthResponse, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		marshaled, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(marshaled)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, responseError(res)
+	}
+
+	var auth AuthResponse
+	if err := decode(res, &auth); err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+// tokenExpiry returns when a JWT expires, the zero time if it can't be parsed. The signature isn't checked, that is up to the server.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,275 @@
+package remote_test
+
+import (
+	"boyl/client/pkg/remote"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func token(name string, expiry time.Time) string {
+	payload, _ := json.Marshal(map[string]any{"id": name, "exp": expiry.Unix()})
+	return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+// server is a fake PocketBase, which accepts the tokens in valid and hands out login and renew.
+type server struct {
+	mu     sync.Mutex
+	valid  map[string]bool
+	login  string
+	renew  string
+	logins int
+	// used is the token of the last request for a game
+	used string
+	// renewing is sent to once a renewal arrived, which waits for release if set
+	renewing chan struct{}
+	release  chan struct{}
+	// loggingIn is sent to once a login arrived, which waits for release if set
+	loggingIn chan struct{}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	respond := func(w http.ResponseWriter, token string) {
+		json.NewEncoder(w).Encode(map[string]any{"token": token, "record": map[string]string{"email": "user@example.com"}})
+	}
+	mux.HandleFunc("POST /api/collections/users/auth-with-password", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		loggingIn := s.loggingIn
+		s.mu.Unlock()
+		if loggingIn != nil {
+			loggingIn <- struct{}{}
+			<-s.release
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var body remote.AuthRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Password != "secret" {
+			http.Error(w, `{"message":"Failed to authenticate."}`, http.StatusBadRequest)
+			return
+		}
+		s.logins++
+		respond(w, s.login)
+	})
+	mux.HandleFunc("POST /api/collections/users/auth-refresh", func(w http.ResponseWriter, r *http.Request) {
+		if s.release != nil {
+			s.renewing <- struct{}{}
+			<-s.release
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.valid[r.Header.Get("Authorization")] {
+			http.Error(w, `{"message":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		respond(w, s.renew)
+	})
+	mux.HandleFunc("GET /api/collections/games/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.valid[r.Header.Get("Authorization")] {
+			http.Error(w, `{"message":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		s.used = r.Header.Get("Authorization")
+		fmt.Fprintf(w, `{"id":%q,"name":"Game"}`, r.PathValue("id"))
+	})
+	return mux
+}
+
+func (s *server) accept(tokens ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = map[string]bool{}
+	for _, token := range tokens {
+		s.valid["Bearer "+token] = true
+	}
+}
+
+func TestRefreshBeforeExpiry(t *testing.T) {
+	// the first token is past half of its lifetime right away
+	expiring, renewed := token("a", time.Now()), token("b", time.Now().Add(time.Hour))
+	s := &server{login: expiring, renew: renewed}
+	s.accept(expiring, renewed)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	var stored []string
+	r.OnToken(func(token string) { stored = append(stored, token) })
+	if err := r.Authenticate(context.Background(), "user@example.com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.GetGame(context.Background(), "g1"); err != nil {
+		t.Fatal(err)
+	}
+	if s.used != "Bearer "+renewed {
+		t.Errorf("expected the request to use the renewed token, got %q", s.used)
+	}
+	if r.Token() != renewed {
+		t.Errorf("expected the renewed token, got %q", r.Token())
+	}
+	if len(stored) != 2 || stored[1] != renewed {
+		t.Errorf("expected both tokens to be stored, got %q", stored)
+	}
+	if s.logins != 1 {
+		t.Errorf("expected a single login, got %d", s.logins)
+	}
+}
+
+func TestRequestsDuringRefresh(t *testing.T) {
+	expiring, renewed := token("a", time.Now()), token("b", time.Now().Add(time.Hour))
+	s := &server{login: expiring, renew: renewed, renewing: make(chan struct{}, 1), release: make(chan struct{})}
+	s.accept(expiring, renewed)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	if err := r.Authenticate(context.Background(), "user@example.com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	first := make(chan error)
+	go func() {
+		_, err := r.GetGame(context.Background(), "g1")
+		first <- err
+	}()
+	<-s.renewing
+
+	// the renewal is stuck, other requests go out with the current token instead of waiting for it
+	second := make(chan error)
+	go func() {
+		_, err := r.GetGame(context.Background(), "g2")
+		second <- err
+	}()
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request not to wait for the renewal")
+	}
+	if r.Token() != expiring {
+		t.Errorf("expected the current token until the renewal finished, got %q", r.Token())
+	}
+
+	close(s.release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if r.Token() != renewed {
+		t.Errorf("expected the renewed token, got %q", r.Token())
+	}
+}
+
+func TestLoginAgainAfterRejectedToken(t *testing.T) {
+	first, second := token("a", time.Now().Add(time.Hour)), token("b", time.Now().Add(time.Hour))
+	s := &server{login: first}
+	s.accept(first)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	if err := r.Authenticate(context.Background(), "user@example.com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// e. g. the password was changed on another machine, which invalidates all tokens
+	s.mu.Lock()
+	s.login = second
+	s.mu.Unlock()
+	s.accept(second)
+	game, err := r.GetGame(context.Background(), "g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.ID != "g1" || r.Token() != second || s.logins != 2 {
+		t.Errorf("expected a retry with a new token, got game %v, token %q and %d logins", game, r.Token(), s.logins)
+	}
+}
+
+func TestLogoutDuringLogin(t *testing.T) {
+	first, second := token("a", time.Now().Add(time.Hour)), token("b", time.Now().Add(time.Hour))
+	s := &server{login: first, release: make(chan struct{})}
+	s.accept(first)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	if err := r.Authenticate(context.Background(), "user@example.com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	s.login = second
+	s.loggingIn = make(chan struct{}, 1)
+	s.mu.Unlock()
+	s.accept(second)
+	done := make(chan error)
+	go func() {
+		_, err := r.GetGame(context.Background(), "g1")
+		done <- err
+	}()
+	<-s.loggingIn
+
+	// the login is stuck, the client can still be used in the meantime
+	loggedOut := make(chan struct{})
+	go func() {
+		r.Logout()
+		close(loggedOut)
+	}()
+	select {
+	case <-loggedOut:
+	case <-time.After(5 * time.Second):
+		close(s.release)
+		t.Fatal("expected logging out not to wait for the login")
+	}
+
+	close(s.release)
+	if err := <-done; !errors.Is(err, remote.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized after logging out, got %v", err)
+	}
+	if r.Token() != "" {
+		t.Errorf("expected the login to be discarded after logging out, got %q", r.Token())
+	}
+}
+
+func TestResume(t *testing.T) {
+	stored, renewed := token("a", time.Now().Add(time.Hour)), token("b", time.Now().Add(time.Hour))
+	s := &server{renew: renewed}
+	s.accept(stored, renewed)
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	var current string
+	r.OnToken(func(token string) { current = token })
+	if err := r.Resume(context.Background(), "user@example.com", stored); err != nil {
+		t.Fatal(err)
+	}
+	if current != renewed || r.Identity() != "user@example.com" {
+		t.Errorf("expected the renewed token to be stored, got %q for %q", current, r.Identity())
+	}
+
+	// without the password there is no way to log in again
+	s.accept()
+	if _, err := r.GetGame(context.Background(), "g1"); !errors.Is(err, remote.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	if err := r.Resume(context.Background(), "user@example.com", stored); !errors.Is(err, remote.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	if current != "" || r.Identity() != "" {
+		t.Errorf("expected the rejected token to be removed, got %q for %q", current, r.Identity())
+	}
+}
@@ -0,0 +1,187 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// The statuses of a game, set when the server scans its games directory.
+const (
+	StatusFound   = "found"
+	StatusMissing = "missing"
+	StatusInvalid = "invalid"
+	StatusDeleted = "deleted"
+)
+
+// Game is a game of the server along with its metadata.
+type Game struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Path        string         `json:"path"`
+	Executable  string         `json:"executable"`
+	Status      string         `json:"status"`
+	Corrupt     bool           `json:"corrupt"`
+	Version     string         `json:"version"`
+	Summary     string         `json:"summary"`
+	Released    types.DateTime `json:"released"`
+	Rating      float64        `json:"rating"`
+	AgeRating   int            `json:"ageRating"`
+	Genres      []string       `json:"genres"`
+	Cover       string         `json:"cover"`
+	Artworks    []string       `json:"artworks"`
+	Screenshots []string       `json:"screenshots"`
+	Provider    string         `json:"provider"`
+	ProviderID  string         `json:"providerId"`
+	Created     types.DateTime `json:"created"`
+	Updated     types.DateTime `json:"updated"`
+}
+
+// GameQuery selects games of the server. The conditions that are set all have to match.
+type GameQuery struct {
+	// Search matches games whose name contains it.
+	Search string
+	// Genres matches games having all of them.
+	Genres []string
+	// Statuses matches games having one of them.
+	Statuses []string
+	// MinRating matches games rated at least as high.
+	MinRating float64
+	// Year matches games released in it.
+	Year int
+	// Filter is a PocketBase filter for anything else, e. g. "corrupt = false".
+	Filter string
+	// Sort is a PocketBase sort, e. g. "-rating,name". Games are sorted by name by default.
+	Sort string
+	// Page starts at 1, PerPage defaults to 30 on the server.
+	Page    int
+	PerPage int
+}
+
+// GamePage is a page of the games matching a query.
+type GamePage struct {
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+	TotalItems int    `json:"totalItems"`
+	TotalPages int    `json:"totalPages"`
+	Items      []Game `json:"items"`
+}
+
+// quote returns s as a string of a PocketBase filter. Backslashes are dropped, since a trailing one would escape the closing quote.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, "")
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func (q GameQuery) filter() string {
+	var conditions []string
+	if q.Search != "" {
+		conditions = append(conditions, "name ~ "+quote(q.Search))
+	}
+	// genres are a JSON array, so the quotes keep e. g. "Action" from matching "Action RPG"
+	for _, genre := range q.Genres {
+		conditions = append(conditions, "genres ~ "+quote(`"`+genre+`"`))
+	}
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, len(q.Statuses))
+		for i, status := range q.Statuses {
+			statuses[i] = "status = " + quote(status)
+		}
+		conditions = append(conditions, "("+strings.Join(statuses, " || ")+")")
+	}
+	if q.MinRating > 0 {
+		conditions = append(conditions, "rating >= "+strconv.FormatFloat(q.MinRating, 'f', -1, 64))
+	}
+	if q.Year > 0 {
+		conditions = append(conditions, fmt.Sprintf(`released >= "%04d-01-01 00:00:00.000Z" && released < "%04d-01-01 00:00:00.000Z"`, q.Year, q.Year+1))
+	}
+	if q.Filter != "" {
+		conditions = append(conditions, "("+q.Filter+")")
+	}
+	return strings.Join(conditions, " && ")
+}
+
+func (q GameQuery) values() url.Values {
+	values := url.Values{"sort": {"name"}}
+	if filter := q.filter(); filter != "" {
+		values.Set("filter", filter)
+	}
+	if q.Sort != "" {
+		values.Set("sort", q.Sort)
+	}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if q.PerPage > 0 {
+		values.Set("perPage", strconv.Itoa(q.PerPage))
+	}
+	return values
+}
+
+// ListGames returns a page of the games matching query.
+func (r *Client) ListGames(ctx context.Context, query GameQuery) (*GamePage, error) {
+	var res GamePage
+	err := r.fetch(ctx, http.MethodGet, "/api/collections/games/records?"+query.values().Encode(), nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *Client) GetGame(ctx context.Context, id string) (*Game, error) {
+	var res Game
+	err := r.fetch(ctx, "GET", "/api/collections/games/records/"+url.PathEscape(id), nil, &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SearchGames returns the first games of the server whose name contains query, sorted by name.
+func (r *Client) SearchGames(ctx context.Context, query string) ([]Game, error) {
+	page, err := r.ListGames(ctx, GameQuery{Search: query, PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+var ErrNoCover = errors.New("game has no cover")
+
+// Cover writes the cover image of a game to w.
+func (r *Client) Cover(ctx context.Context, game *Game, w io.Writer) error {
+	if game.Cover == "" {
+		return ErrNoCover
+	}
+	return r.File(ctx, game, game.Cover, w)
+}
+
+// FileURL returns the URL of a file of a game, e. g. one of its screenshots.
+func (r *Client) FileURL(game *Game, name string) string {
+	return r.URL + "/api/files/games/" + url.PathEscape(game.ID) + "/" + url.PathEscape(name)
+}
+
+// File writes a file of a game to w.
+func (r *Client) File(ctx context.Context, game *Game, name string, w io.Writer) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.FileURL(game, name), nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
@@ -0,0 +1,68 @@
+package remote_test
+
+import (
+	"boyl/client/pkg/remote"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestListGames(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    remote.GameQuery
+		expected url.Values
+	}{
+		{"everything", remote.GameQuery{}, url.Values{"sort": {"name"}}},
+		{
+			"search",
+			remote.GameQuery{Search: `The "Best" Game\`, Page: 2, PerPage: 10},
+			url.Values{"sort": {"name"}, "filter": {`name ~ "The \"Best\" Game"`}, "page": {"2"}, "perPage": {"10"}},
+		},
+		{
+			"metadata",
+			remote.GameQuery{Genres: []string{"Action", "Indie"}, Statuses: []string{remote.StatusFound, remote.StatusMissing}, MinRating: 72.5, Year: 2015, Sort: "-rating"},
+			url.Values{"sort": {"-rating"}, "filter": {`genres ~ "\"Action\"" && genres ~ "\"Indie\"" && (status = "found" || status = "missing") && rating >= 72.5 && released >= "2015-01-01 00:00:00.000Z" && released < "2016-01-01 00:00:00.000Z"`}},
+		},
+		{
+			"custom filter",
+			remote.GameQuery{Search: "doom", Filter: "corrupt = false || version != ''"},
+			url.Values{"sort": {"name"}, "filter": {`name ~ "doom" && (corrupt = false || version != '')`}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received url.Values
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/collections/games/records" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				received = r.URL.Query()
+				w.Write([]byte(`{"page":2,"perPage":10,"totalItems":11,"totalPages":2,"items":[{
+					"id":"g1","name":"Doom","status":"found","released":"1993-12-10 00:00:00.000Z","rating":91.5,"ageRating":18,
+					"genres":["Shooter"],"cover":"cover.jpg","screenshots":["a.png","b.png"],"providerId":"123"
+				}]}`))
+			}))
+			defer ts.Close()
+
+			page, err := remote.New(ts.URL).ListGames(context.Background(), test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if received.Encode() != test.expected.Encode() {
+				t.Errorf("expected query %v, got %v", test.expected, received)
+			}
+
+			if page.TotalPages != 2 || len(page.Items) != 1 {
+				t.Fatalf("unexpected page %+v", page)
+			}
+			game := page.Items[0]
+			if game.Released.Time().Year() != 1993 || game.Rating != 91.5 || game.AgeRating != 18 || len(game.Genres) != 1 || len(game.Screenshots) != 2 || game.ProviderID != "123" {
+				t.Errorf("unexpected game %+v", game)
+			}
+		})
+	}
+}
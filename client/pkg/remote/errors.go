@@ -0,0 +1,114 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when the server rejects the token and the client can't log in again by itself.
+	ErrUnauthorized = errors.New("not logged in")
+	// ErrNotFound is returned for records that don't exist on the server or that the user isn't allowed to see.
+	ErrNotFound = errors.New("not found on the server")
+	// ErrUnavailable is returned when the server can't be reached, is overloaded or limits the requests of the user.
+	ErrUnavailable = errors.New("server unavailable")
+	// ErrVersionMismatch is returned for responses the client doesn't understand, e. g. from an older server or from
+	// something that isn't a server at all.
+	ErrVersionMismatch = errors.New("server version isn't supported")
+	// ErrUnsupported is returned for features the server doesn't report in its info, e. g. because it is older than the client.
+	ErrUnsupported = errors.New("not supported by the server")
+)
+
+// Error is an error response of the server. It matches the error variables of the package with errors.Is.
+type Error struct {
+	Status  int
+	Message string
+	// Data holds the validation errors of the fields of a record, keyed by field.
+	Data map[string]any
+	// RetryAfter is how long the server asked to wait before trying again, if it limits the requests of the user.
+	RetryAfter time.Duration
+	// unknown is set if the body wasn't an error of PocketBase.
+	unknown bool
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%s (%d)", e.Message, e.Status)
+	if e.Message == "" {
+		message = fmt.Sprintf("unexpected status code: %d", e.Status)
+	}
+	if e.RetryAfter > 0 {
+		message += fmt.Sprintf(", try again in %s", e.RetryAfter)
+	}
+	return message
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrUnavailable:
+		return e.Status == http.StatusBadGateway || e.Status == http.StatusServiceUnavailable || e.Status == http.StatusGatewayTimeout ||
+			e.Status == http.StatusTooManyRequests
+	case ErrVersionMismatch:
+		return e.unknown && e.Status < 500
+	}
+	return false
+}
+
+// StatusError returns the error for a response whose body isn't available, e. g. of a download through grab.
+func StatusError(res *http.Response) error {
+	return &Error{Status: res.StatusCode, Message: http.StatusText(res.StatusCode), RetryAfter: retryAfter(res.Header)}
+}
+
+// retryAfter parses the Retry-After header in seconds, the servers don't send dates.
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// responseError reads the error body of a response.
+func responseError(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	apiErr := &Error{Status: res.StatusCode, RetryAfter: retryAfter(res.Header)}
+
+	var parsed struct {
+		Message string         `json:"message"`
+		Data    map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil || parsed.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+		apiErr.unknown = true
+		return apiErr
+	}
+	apiErr.Message = parsed.Message
+	apiErr.Data = parsed.Data
+	return apiErr
+}
+
+// requestError wraps errors of requests that didn't get a response, including timeouts. Cancellation by the caller is returned as is.
+func requestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
+
+// decode reads the JSON body of a successful response into v.
+func decode(res *http.Response, v any) error {
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrVersionMismatch, err)
+	}
+	return nil
+}
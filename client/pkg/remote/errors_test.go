@@ -0,0 +1,93 @@
+package remote_test
+
+import (
+	"boyl/client/pkg/remote"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+		message  string
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"status":401,"message":"The request requires valid record authorization token.","data":{}}`, remote.ErrUnauthorized, "The request requires valid record authorization token."},
+		{"not found", http.StatusNotFound, `{"status":404,"message":"The requested resource wasn't found.","data":{}}`, remote.ErrNotFound, "The requested resource wasn't found."},
+		{"proxy", http.StatusBadGateway, `<html>Bad Gateway</html>`, remote.ErrUnavailable, "<html>Bad Gateway</html>"},
+		{"maintenance", http.StatusServiceUnavailable, ``, remote.ErrUnavailable, ""},
+		{"not a server", http.StatusMethodNotAllowed, `<html>Method Not Allowed</html>`, remote.ErrVersionMismatch, "<html>Method Not Allowed</html>"},
+		{"not json", http.StatusOK, `<html>Welcome</html>`, remote.ErrVersionMismatch, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer ts.Close()
+
+			_, err := remote.New(ts.URL).GetGame(context.Background(), "g1")
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			var apiErr *remote.Error
+			if test.status != http.StatusOK && (!errors.As(err, &apiErr) || apiErr.Message != test.message) {
+				t.Errorf("expected message %q, got %v", test.message, err)
+			}
+		})
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	r := remote.New(ts.URL)
+	r.Timeout = 10 * time.Millisecond
+	if _, err := r.GetGame(context.Background(), "g1"); !errors.Is(err, remote.ErrUnavailable) {
+		t.Errorf("expected a timeout to be ErrUnavailable, got %v", err)
+	}
+
+	// cancellation isn't a problem of the server
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.GetGame(ctx, "g1"); !errors.Is(err, context.Canceled) || errors.Is(err, remote.ErrUnavailable) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	ts.Close()
+	if _, err := r.GetGame(context.Background(), "g1"); !errors.Is(err, remote.ErrUnavailable) {
+		t.Errorf("expected a closed server to be ErrUnavailable, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"status":429,"message":"at most 1 downloads can run at once","data":{}}`))
+	}))
+	defer ts.Close()
+
+	_, err := remote.New(ts.URL).GetGame(context.Background(), "g1")
+	if !errors.Is(err, remote.ErrUnavailable) {
+		t.Fatalf("expected a limited request to be ErrUnavailable, got %v", err)
+	}
+	var apiErr *remote.Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 30*time.Second {
+		t.Errorf("expected to retry after 30s, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package remote
+
+import (
+	"boyl/pkg/api"
+	"boyl/pkg/archive"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Check returns an error matching ErrVersionMismatch if the client can't talk to the server at all.
+func Check(info *api.Info) error {
+	if info.MinProtocol > api.Protocol {
+		return fmt.Errorf("%w: the server (version %s) needs a newer client, please update it", ErrVersionMismatch, info.Version)
+	}
+	if info.Protocol < api.MinProtocol {
+		return fmt.Errorf("%w: the server (version %s) is too old for the client, please update the server", ErrVersionMismatch, info.Version)
+	}
+	return nil
+}
+
+// Warning describes what doesn't work with a server the client can talk to, empty if everything does.
+func Warning(info *api.Info) string {
+	var warnings []string
+	if info.Legacy {
+		warnings = append(warnings, "the server doesn't report its version, playtime and saves aren't synced until it is updated")
+	} else if info.Protocol > api.Protocol {
+		warnings = append(warnings, fmt.Sprintf("the server (version %s) is newer than the client, please update the client", info.Version))
+	}
+	var unsupported []string
+	for _, format := range info.Formats {
+		if !slices.Contains(archive.Formats, format) {
+			unsupported = append(unsupported, format)
+		}
+	}
+	if len(unsupported) > 0 {
+		warnings = append(warnings, fmt.Sprintf("games in %s archives can't be installed", strings.Join(unsupported, ", ")))
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// Info returns the info of the server fetched when logging in, nil if the client didn't reach the server yet.
+func (r *Client) Info() *api.Info {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.info
+}
+
+// Supports reports whether the server has a feature. It is assumed to have it as long as its info is unknown,
+// requests fail on their own then.
+func (r *Client) Supports(feature string) bool {
+	info := r.Info()
+	return info == nil || info.Supports(feature)
+}
+
+// Connect fetches the info of the server again, e. g. after it was updated, and checks the client can talk to it.
+func (r *Client) Connect(ctx context.Context) (*api.Info, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	err := r.connect(ctx)
+	return r.info, err
+}
+
+// require returns ErrUnsupported if the server doesn't have a feature.
+func (r *Client) require(feature string) error {
+	if !r.Supports(feature) {
+		return fmt.Errorf("%w: %s", ErrUnsupported, feature)
+	}
+	return nil
+}
+
+// connect fetches the info of the server and checks the client can talk to it. Requests to an incompatible server fail
+// with the same error from then on, instead of failing to decode what the server sent. The caller must hold mu.
+func (r *Client) connect(ctx context.Context) error {
+	info, err := r.fetchInfo(ctx)
+	if err != nil {
+		// the last check still applies if the server can't be reached
+		return err
+	}
+	r.info = info
+	r.incompatible = Check(info)
+	return r.incompatible
+}
+
+// fetchInfo is sent without the auth transport, like authRequest, since the info is fetched while logging in.
+func (r *Client) fetchInfo(ctx context.Context) (*api.Info, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL+"/api/info", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err := responseError(res)
+		if errors.Is(err, ErrNotFound) {
+			return &api.Info{Version: "unknown", Protocol: 1, MinProtocol: 1, Legacy: true}, nil
+		}
+		return nil, err
+	}
+
+	var info api.Info
+	if err := decode(res, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,78 @@
+package remote_test
+
+import (
+	"boyl/client/pkg/remote"
+	"boyl/pkg/api"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *api.Info
+		expected error
+		playtime bool
+		warning  bool
+	}{
+		{"same protocol", &api.Info{Version: "v1", Protocol: api.Protocol, MinProtocol: api.Protocol, Features: []string{api.FeaturePlaytime}}, nil, true, false},
+		{"newer server", &api.Info{Version: "v2", Protocol: api.Protocol + 1, MinProtocol: api.Protocol, Features: []string{api.FeaturePlaytime}}, nil, true, true},
+		{"client too old", &api.Info{Version: "v3", Protocol: api.Protocol + 1, MinProtocol: api.Protocol + 1}, remote.ErrVersionMismatch, true, false},
+		{"server too old", &api.Info{Version: "v0", Protocol: api.MinProtocol - 1, MinProtocol: 0}, remote.ErrVersionMismatch, true, false},
+		{"unknown format", &api.Info{Version: "v1", Protocol: api.Protocol, MinProtocol: api.Protocol, Formats: []string{"zip", "arj"}}, nil, false, true},
+		{"before the endpoint", nil, nil, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			login := token("a", time.Now().Add(time.Hour))
+			mux := http.NewServeMux()
+			if test.info != nil {
+				mux.HandleFunc("GET /api/info", func(w http.ResponseWriter, r *http.Request) {
+					json.NewEncoder(w).Encode(test.info)
+				})
+			}
+			mux.HandleFunc("POST /api/collections/users/auth-with-password", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(map[string]any{"token": login})
+			})
+			mux.HandleFunc("GET /api/collections/games/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"id":%q,"name":"Game"}`, r.PathValue("id"))
+			})
+			mux.HandleFunc("POST /api/playtime", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"synced":0,"skipped":0}`))
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			r := remote.New(ts.URL)
+			err := r.Authenticate(context.Background(), "user@example.com", "secret")
+			if !errors.Is(err, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, err)
+			}
+			// requests to an incompatible server fail with the same error instead of decoding whatever it sends
+			if _, err := r.GetGame(context.Background(), "g1"); !errors.Is(err, test.expected) {
+				t.Errorf("expected %v for a request, got %v", test.expected, err)
+			}
+			if test.expected != nil {
+				return
+			}
+
+			if r.Supports(api.FeaturePlaytime) != test.playtime {
+				t.Errorf("expected support for playtime to be %v", test.playtime)
+			}
+			_, err = r.SyncPlaytime(context.Background(), nil)
+			if test.playtime != (err == nil) || (!test.playtime && !errors.Is(err, remote.ErrUnsupported)) {
+				t.Errorf("expected playtime sync to fail only without the feature, got %v", err)
+			}
+			if warning := remote.Warning(r.Info()); (warning != "") != test.warning {
+				t.Errorf("expected a warning: %v, got %q", test.warning, warning)
+			}
+		})
+	}
+}
@@ -1,118 +1,132 @@
 package remote
 
 import (
+	"boyl/pkg/api"
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+	"sync"
+	"time"
 )
 
-type bearerAuthTransport struct {
-	inner http.RoundTripper
-	token string
-}
-
-func (b *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", "Bearer "+b.token)
-	return b.inner.RoundTrip(req)
-}
+// DefaultTimeout is how long requests to the API of the server may take, which doesn't apply to transfers of files.
+const DefaultTimeout = 30 * time.Second
 
 type Client struct {
-	URL      string
-	client   *http.Client
+	URL string
+	// Timeout limits requests to the API of the server, zero means no limit.
+	Timeout time.Duration
+	client  *http.Client
+
+	mu       sync.Mutex
 	identity string
+	token    string
+	// password is only kept in memory to log in again once the token was rejected
+	password  string
+	refreshAt time.Time
+	// refreshing is set while the token is renewed, see refreshIfDue
+	refreshing bool
+	onToken    func(token string)
+	info       *api.Info
+	// incompatible is the error of the last compatibility check, requests fail with it
+	incompatible error
 }
 
 func New(url string) *Client {
-	return &Client{
-		URL:    url,
-		client: http.DefaultClient,
+	r := &Client{URL: url, Timeout: DefaultTimeout}
+	r.client = &http.Client{
+		Transport: &authTransport{inner: http.DefaultTransport, remote: r},
 	}
+	return r
 }
+
 func (r *Client) Identity() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return r.identity
 }
 
+// Client returns the HTTP client sending requests with the token of the user, e. g. for downloads.
 func (r *Client) Client() *http.Client {
 	return r.client
 }
 
-func (r *Client) fetch(method, path string, body any, v any) error {
-	marshaled, err := json.Marshal(body)
-	if err != nil {
-		return err
+// withTimeout limits ctx to the timeout of API requests.
+func (r *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, r.Timeout)
+}
 
-	req, err := http.NewRequest(method, r.URL+path, bytes.NewBuffer(marshaled))
-	if err != nil {
-		return err
+// do sends a request and turns failed requests and error responses into the errors of the package.
+// The body of the returned response must be closed.
+func (r *Client) do(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	incompatible := r.incompatible
+	r.mu.Unlock()
+	if incompatible != nil {
+		return nil, incompatible
 	}
-	req.Header.Set("Content-Type", "application/json")
 
 	res, err := r.client.Do(req)
 	if err != nil {
-		return err
+		return nil, requestError(err)
 	}
-	defer res.Body.Close()
-
 	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", res.StatusCode, body)
-	}
-
-	if v != nil {
-		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
-			return err
-		}
+		defer res.Body.Close()
+		return nil, responseError(res)
 	}
-	return nil
+	return res, nil
 }
 
-type AuthRequest struct {
-	Identity string `json:"identity"`
-	Password string `json:"password"`
-}
-type AuthResponse struct {
-	Token string `json:"token"`
-}
+func (r *Client) fetch(ctx context.Context, method, path string, body any, v any) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-func (r *Client) Authenticate(email, password string) error {
-	var res AuthResponse
-	err := r.fetch(
-		"POST",
-		"/api/collections/users/auth-with-password", AuthRequest{
-			Identity: email,
-			Password: password,
-		},
-		&res,
-	)
+	marshaled, err := json.Marshal(body)
 	if err != nil {
 		return err
 	}
 
-	r.client = &http.Client{
-		Transport: &bearerAuthTransport{
-			inner: http.DefaultTransport,
-			token: res.Token,
-		},
+	req, err := http.NewRequestWithContext(ctx, method, r.URL+path, bytes.NewBuffer(marshaled))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	r.identity = email
+	res, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
 
+	if v != nil {
+		return decode(res, v)
+	}
 	return nil
 }
 
-type Game struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Path       string `json:"path"`
-	Executable string `json:"executable"`
+// Session is the summary of a play session sent to the server.
+type Session struct {
+	ID       string    `json:"id"`
+	Game     string    `json:"game"`
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration"`
 }
 
-func (r *Client) GetGame(id string) (*Game, error) {
-	var res Game
-	err := r.fetch("GET", "/api/collections/games/records/"+id, nil, &res)
+type SyncResult struct {
+	Synced  int `json:"synced"`
+	Skipped int `json:"skipped"`
+}
+
+func (r *Client) SyncPlaytime(ctx context.Context, sessions []Session) (*SyncResult, error) {
+	if err := r.require(api.FeaturePlaytime); err != nil {
+		return nil, err
+	}
+	var res SyncResult
+	err := r.fetch(ctx, "POST", "/api/playtime", map[string]any{"sessions": sessions}, &res)
 	if err != nil {
 		return nil, err
 	}
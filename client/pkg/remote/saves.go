@@ -0,0 +1,119 @@
+package remote
+
+import (
+	"boyl/pkg/api"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrSaveConflict is returned by UploadSave when another machine uploaded saves since the base version.
+var ErrSaveConflict = errors.New("saves were changed on another machine")
+
+// Save is a version of the saves of a game on the server.
+type Save struct {
+	ID      string `json:"id"`
+	Game    string `json:"game"`
+	Version int    `json:"version"`
+	Hash    string `json:"hash"`
+	Machine string `json:"machine"`
+	Size    int64  `json:"size"`
+	Created string `json:"created"`
+}
+
+// LatestSave returns the newest saves of a game uploaded by the user, or nil if there are none.
+func (r *Client) LatestSave(ctx context.Context, game string) (*Save, error) {
+	if err := r.require(api.FeatureSaves); err != nil {
+		return nil, err
+	}
+	var save Save
+	err := r.fetch(ctx, http.MethodGet, "/api/saves/latest?"+url.Values{"game": {game}}.Encode(), nil, &save)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// UploadSave uploads the archive at path as the next version of the saves of a game. base is the version the client synced last.
+func (r *Client) UploadSave(ctx context.Context, game string, base int, hash, machine, path string, force bool) (*Save, error) {
+	if err := r.require(api.FeatureSaves); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// streamed, since saves of some games are hundreds of megabytes
+	body, w := io.Pipe()
+	form := multipart.NewWriter(w)
+	go func() {
+		part, err := form.CreateFormFile("archive", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		w.CloseWithError(err)
+	}()
+
+	query := url.Values{
+		"game":    {game},
+		"base":    {strconv.Itoa(base)},
+		"hash":    {hash},
+		"machine": {machine},
+		"force":   {strconv.FormatBool(force)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL+"/api/saves?"+query.Encode(), body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	res, err := r.do(req)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+		return nil, ErrSaveConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var save Save
+	if err := decode(res, &save); err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// DownloadSave writes the archive of a save version to w.
+func (r *Client) DownloadSave(ctx context.Context, id string, w io.Writer) error {
+	if err := r.require(api.FeatureSaves); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL+"/api/saves/download?"+url.Values{"id": {id}}.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
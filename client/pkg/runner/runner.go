@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindWine   Kind = "wine"
+	KindProton Kind = "proton"
+)
+
+// DataDirectory is created inside of the install directory of a game and holds everything a runner creates, e. g. the wine prefix.
+// It is kept when a game is reinstalled.
+const DataDirectory = ".boyl"
+
+// Runner runs windows executables on other operating systems. The path identifies a runner.
+type Runner struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+	// Path is the wine binary or the proton script.
+	Path string `json:"path"`
+}
+
+// Options are the per-game settings of a runner.
+type Options struct {
+	// Prefix is the directory holding the wine prefix, for proton the compat data directory containing it.
+	Prefix string
+	// DLLOverrides is passed as WINEDLLOVERRIDES, e. g. "d3d9=n,b;dinput8=n,b".
+	DLLOverrides string
+	Env          map[string]string
+}
+
+// PrefixPath returns the prefix directory for a game installed at installDirectory.
+func PrefixPath(installDirectory string) string {
+	return filepath.Join(installDirectory, DataDirectory, "prefix")
+}
+
+// Command returns the command and arguments that start executable through the runner, along with the environment variables to set.
+// The prefix directory is created if it doesn't exist.
+func (r *Runner) Command(executable string, args []string, options Options) (string, []string, []string, error) {
+	env := map[string]string{}
+	maps.Copy(env, options.Env)
+	if options.DLLOverrides != "" {
+		env["WINEDLLOVERRIDES"] = options.DLLOverrides
+	}
+
+	if options.Prefix != "" {
+		if err := os.MkdirAll(options.Prefix, 0755); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	var name string
+	var runnerArgs []string
+
+	switch r.Kind {
+	case KindWine:
+		if options.Prefix != "" {
+			env["WINEPREFIX"] = options.Prefix
+		}
+		name = r.Path
+		runnerArgs = append([]string{executable}, args...)
+	case KindProton:
+		if options.Prefix != "" {
+			env["STEAM_COMPAT_DATA_PATH"] = options.Prefix
+		}
+		// proton refuses to start without it, but only uses it to find the steam runtime
+		if _, ok := env["STEAM_COMPAT_CLIENT_INSTALL_PATH"]; !ok {
+			env["STEAM_COMPAT_CLIENT_INSTALL_PATH"] = steamRoot()
+		}
+		name = r.Path
+		runnerArgs = append([]string{"run", executable}, args...)
+	default:
+		return "", nil, nil, fmt.Errorf("unknown runner kind %s", r.Kind)
+	}
+
+	keys := slices.Sorted(maps.Keys(env))
+	environ := make([]string, 0, len(keys))
+	for _, key := range keys {
+		environ = append(environ, key+"="+env[key])
+	}
+
+	return name, runnerArgs, environ, nil
+}
+
+// Find returns the runner with the given path from the detected and configured runners.
+func Find(runners []Runner, path string) (*Runner, error) {
+	for _, runner := range runners {
+		if runner.Path == path {
+			return &runner, nil
+		}
+	}
+	return nil, fmt.Errorf("runner %s not found", path)
+}
+
+// NeedsRunner reports whether the executable can't be started natively on this operating system.
+func NeedsRunner(goos, executable string) bool {
+	if goos == "windows" {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(executable))
+	return ext == ".exe" || ext == ".bat" || ext == ".msi"
+}
+
+// Detect returns the wine and proton builds installed on the system, followed by the custom runners.
+func Detect(custom []Runner) []Runner {
+	var runners []Runner
+	seen := make(map[string]bool)
+	add := func(runner Runner) {
+		resolved, err := filepath.EvalSymlinks(runner.Path)
+		if err != nil || seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		runners = append(runners, runner)
+	}
+
+	if path, err := exec.LookPath("wine"); err == nil {
+		add(Runner{Name: "Wine (system)", Kind: KindWine, Path: path})
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		for _, dir := range []string{
+			filepath.Join(home, ".local/share/lutris/runners/wine"),
+			filepath.Join(home, ".var/app/net.lutris.Lutris/data/lutris/runners/wine"),
+		} {
+			for _, path := range glob(filepath.Join(dir, "*", "bin", "wine")) {
+				add(Runner{Name: filepath.Base(filepath.Dir(filepath.Dir(path))), Kind: KindWine, Path: path})
+			}
+		}
+
+		for _, dir := range steamDirectories(home) {
+			for _, pattern := range []string{
+				filepath.Join(dir, "steamapps", "common", "Proton*", "proton"),
+				filepath.Join(dir, "compatibilitytools.d", "*", "proton"),
+			} {
+				for _, path := range glob(pattern) {
+					add(Runner{Name: filepath.Base(filepath.Dir(path)), Kind: KindProton, Path: path})
+				}
+			}
+		}
+	}
+
+	for _, runner := range custom {
+		add(runner)
+	}
+
+	return runners
+}
+
+func glob(pattern string) []string {
+	matches, _ := filepath.Glob(pattern)
+	slices.Sort(matches)
+	return matches
+}
+
+func steamDirectories(home string) []string {
+	return []string{
+		filepath.Join(home, ".steam", "root"),
+		filepath.Join(home, ".steam", "steam"),
+		filepath.Join(home, ".local", "share", "Steam"),
+		filepath.Join(home, ".var", "app", "com.valvesoftware.Steam", "data", "Steam"),
+	}
+}
+
+// steamRoot returns the steam installation, or the default location if steam isn't installed.
+func steamRoot() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	directories := steamDirectories(home)
+	for _, dir := range directories {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+	}
+	return directories[0]
+}
+
+// DriveC returns the C: drive of the prefix.
+func (r *Runner) DriveC(prefix string) string {
+	if r.Kind == KindProton {
+		return filepath.Join(prefix, "pfx", "drive_c")
+	}
+	return filepath.Join(prefix, "drive_c")
+}
+
+// User returns the name of the windows user inside of the prefix.
+func (r *Runner) User() string {
+	if r.Kind == KindProton {
+		return "steamuser"
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,120 @@
+package runner_test
+
+import (
+	"boyl/client/pkg/runner"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeStub writes a runner that prints its arguments and the wine related environment.
+func writeStub(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub runner is a shell script")
+	}
+
+	path := filepath.Join(t.TempDir(), "wine")
+	script := "#!/bin/sh\n" +
+		`echo "args=$*"` + "\n" +
+		`echo "WINEPREFIX=$WINEPREFIX"` + "\n" +
+		`echo "STEAM_COMPAT_DATA_PATH=$STEAM_COMPAT_DATA_PATH"` + "\n" +
+		`echo "WINEDLLOVERRIDES=$WINEDLLOVERRIDES"` + "\n" +
+		`echo "DXVK_HUD=$DXVK_HUD"` + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func run(t *testing.T, r *runner.Runner, options runner.Options) map[string]string {
+	t.Helper()
+	name, args, env, err := r.Command("C:/game/game.exe", []string{"-windowed", "-skip intro"}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		values[key] = value
+	}
+	return values
+}
+
+func TestWine(t *testing.T) {
+	stub := writeStub(t)
+	prefix := filepath.Join(t.TempDir(), "game", runner.DataDirectory, "prefix")
+
+	values := run(t, &runner.Runner{Name: "stub", Kind: runner.KindWine, Path: stub}, runner.Options{
+		Prefix:       prefix,
+		DLLOverrides: "d3d9=n,b",
+		Env:          map[string]string{"DXVK_HUD": "fps"},
+	})
+
+	expected := map[string]string{
+		"args":                   "C:/game/game.exe -windowed -skip intro",
+		"WINEPREFIX":             prefix,
+		"STEAM_COMPAT_DATA_PATH": "",
+		"WINEDLLOVERRIDES":       "d3d9=n,b",
+		"DXVK_HUD":               "fps",
+	}
+	for key, value := range expected {
+		if values[key] != value {
+			t.Errorf("expected %s to be %q, got %q", key, value, values[key])
+		}
+	}
+
+	if info, err := os.Stat(prefix); err != nil || !info.IsDir() {
+		t.Errorf("expected prefix %s to be created", prefix)
+	}
+}
+
+func TestProton(t *testing.T) {
+	stub := writeStub(t)
+	prefix := filepath.Join(t.TempDir(), "prefix")
+
+	values := run(t, &runner.Runner{Name: "stub", Kind: runner.KindProton, Path: stub}, runner.Options{
+		Prefix: prefix,
+	})
+
+	if values["args"] != "run C:/game/game.exe -windowed -skip intro" {
+		t.Errorf("unexpected args %q", values["args"])
+	}
+	if values["STEAM_COMPAT_DATA_PATH"] != prefix {
+		t.Errorf("expected STEAM_COMPAT_DATA_PATH to be %q, got %q", prefix, values["STEAM_COMPAT_DATA_PATH"])
+	}
+	if values["WINEPREFIX"] != "" {
+		t.Errorf("expected WINEPREFIX to be unset, got %q", values["WINEPREFIX"])
+	}
+}
+
+func TestDetectCustom(t *testing.T) {
+	stub := writeStub(t)
+	custom := []runner.Runner{
+		{Name: "stub", Kind: runner.KindWine, Path: stub},
+		{Name: "missing", Kind: runner.KindWine, Path: filepath.Join(t.TempDir(), "missing")},
+	}
+
+	runners := runner.Detect(custom)
+	r, err := runner.Find(runners, stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "stub" {
+		t.Errorf("expected stub runner, got %+v", r)
+	}
+	if _, err := runner.Find(runners, custom[1].Path); err == nil {
+		t.Errorf("expected missing runner to be skipped")
+	}
+}
@@ -0,0 +1,127 @@
+package saves
+
+import (
+	"boyl/client/pkg/launch"
+	"boyl/client/pkg/settings"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Variables are the values of the placeholders in save paths, e. g. {appdata}.
+type Variables map[string]string
+
+var placeholder = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// Path is a save path definition along with where it points to on this system.
+type Path struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path"`
+	Exists  bool   `json:"exists"`
+}
+
+// Expand replaces the placeholders in pattern. It fails for unknown placeholders, e. g. {appdata} for a native linux game.
+func (v Variables) Expand(pattern string) (string, bool) {
+	ok := true
+	expanded := placeholder.ReplaceAllStringFunc(pattern, func(match string) string {
+		value, found := v[strings.Trim(match, "{}")]
+		if !found {
+			ok = false
+		}
+		return value
+	})
+	if !ok {
+		return "", false
+	}
+	return filepath.Clean(filepath.FromSlash(expanded)), true
+}
+
+// GameVariables returns the placeholders for a client game. Windows folders point into the wine prefix when the game uses a runner.
+//
+//	{home}          home directory of the user
+//	{install}       install directory of the game
+//	{appdata}       AppData/Roaming
+//	{localappdata}  AppData/Local
+//	{locallow}      AppData/LocalLow
+//	{documents}     Documents
+//	{savedgames}    Saved Games
+//	{prefix}        C: drive of the wine prefix
+//	{xdgdata}       XDG_DATA_HOME
+//	{xdgconfig}     XDG_CONFIG_HOME
+func GameVariables(game *core.Record, s *settings.Settings) (Variables, error) {
+	profile, err := launch.FindProfile(game, launch.MainProfile)
+	if err != nil {
+		return nil, err
+	}
+	options, err := launch.GameOptions(game, s, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	v := Variables{
+		"home":    home,
+		"install": game.GetString("path"),
+	}
+
+	switch {
+	case options.Runner != nil:
+		driveC := options.Runner.DriveC(options.Prefix)
+		profileDirectory := filepath.Join(driveC, "users", options.Runner.User())
+		v["prefix"] = driveC
+		v.addWindows(profileDirectory)
+	case runtime.GOOS == "windows":
+		v.addWindows(os.Getenv("USERPROFILE"))
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			v["appdata"] = appData
+		}
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			v["localappdata"] = localAppData
+		}
+	default:
+		v["xdgdata"] = xdgDirectory("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+		v["xdgconfig"] = xdgDirectory("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	}
+
+	return v, nil
+}
+
+func (v Variables) addWindows(profileDirectory string) {
+	v["appdata"] = filepath.Join(profileDirectory, "AppData", "Roaming")
+	v["localappdata"] = filepath.Join(profileDirectory, "AppData", "Local")
+	v["locallow"] = filepath.Join(profileDirectory, "AppData", "LocalLow")
+	v["documents"] = filepath.Join(profileDirectory, "Documents")
+	v["savedgames"] = filepath.Join(profileDirectory, "Saved Games")
+}
+
+func xdgDirectory(env, fallback string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GamePaths expands the save paths of a client game. Paths with unknown placeholders are left out.
+func GamePaths(game *core.Record, v Variables) []Path {
+	var patterns []string
+	game.UnmarshalJSONField("savePaths", &patterns)
+
+	var paths []Path
+	for _, pattern := range patterns {
+		path, ok := v.Expand(pattern)
+		if !ok {
+			continue
+		}
+		_, err := os.Stat(path)
+		paths = append(paths, Path{Pattern: pattern, Path: path, Exists: err == nil})
+	}
+	return paths
+}
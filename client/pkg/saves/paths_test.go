@@ -0,0 +1,41 @@
+package saves_test
+
+import (
+	"boyl/client/pkg/saves"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	v := saves.Variables{
+		"install": "/games/Game",
+		"appdata": "/prefix/drive_c/users/player/AppData/Roaming",
+	}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected string
+		ok       bool
+	}{
+		{"install", "{install}/saves", "/games/Game/saves", true},
+		{"appdata", "{appdata}/Studio/Game", "/prefix/drive_c/users/player/AppData/Roaming/Studio/Game", true},
+		{"single file", "{install}/settings.ini", "/games/Game/settings.ini", true},
+		{"cleaned", "{install}/./saves/../saves/", "/games/Game/saves", true},
+		{"no placeholder", "/absolute/saves", "/absolute/saves", true},
+		{"unknown placeholder", "{documents}/My Games", "", false},
+		{"uppercase is literal", "{install}/{SAVES}", "/games/Game/{SAVES}", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := v.Expand(test.pattern)
+			if ok != test.ok {
+				t.Fatalf("Expand(%q) ok = %v, want %v", test.pattern, ok, test.ok)
+			}
+			if got != filepath.FromSlash(test.expected) {
+				t.Errorf("Expand(%q) = %q, want %q", test.pattern, got, test.expected)
+			}
+		})
+	}
+}
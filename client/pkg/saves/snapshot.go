@@ -0,0 +1,477 @@
+package saves
+
+import (
+	"archive/zip"
+	"boyl/client/pkg/settings"
+	"boyl/pkg/archive"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// DefaultRetention is the number of snapshots kept per game if the saveRetention setting isn't set.
+const DefaultRetention = 10
+
+// manifestName is the file in a snapshot describing the save paths it contains.
+const manifestName = "manifest.json"
+
+// restoreLimits stop a snapshot, which might come from the server, from filling the disk. The compression ratio isn't limited,
+// since save files are often mostly zeros.
+var restoreLimits = archive.Limits{
+	MaxTotalBytes: 16 << 30,
+	MaxFiles:      archive.DefaultLimits.MaxFiles,
+	MaxPathLength: archive.DefaultLimits.MaxPathLength,
+}
+
+var ErrNoSavePaths = errors.New("game has no save paths")
+
+type manifest struct {
+	Paths []manifestPath `json:"paths"`
+}
+
+type manifestPath struct {
+	Pattern string `json:"pattern"`
+	// Directory is false for save paths pointing to a single file.
+	Directory bool `json:"directory"`
+	Exists    bool `json:"exists"`
+}
+
+// Manager snapshots save files into zip archives in a directory and records them in the snapshots collection.
+type Manager struct {
+	app        core.App
+	collection *core.Collection
+	settings   *settings.Settings
+	directory  string
+}
+
+func NewManager(app core.App, collection *core.Collection, settings *settings.Settings, directory string) *Manager {
+	return &Manager{
+		app:        app,
+		collection: collection,
+		settings:   settings,
+		directory:  directory,
+	}
+}
+
+func (m *Manager) retention() int {
+	if v := m.settings.GetNumber("saveRetention"); v > 0 {
+		return int(v)
+	}
+	return DefaultRetention
+}
+
+// Paths returns the expanded save paths of a client game.
+func (m *Manager) Paths(game *core.Record) ([]Path, error) {
+	v, err := GameVariables(game, m.settings)
+	if err != nil {
+		return nil, err
+	}
+	return GamePaths(game, v), nil
+}
+
+// file is a save file found while walking the save paths.
+type file struct {
+	// name is the name in the archive, prefixed with the index of the save path.
+	name string
+	path string
+	mode fs.FileMode
+}
+
+// collect returns the files of the save paths, sorted by name so the hash doesn't depend on the walk order.
+func collect(paths []Path) (*manifest, []file, error) {
+	m := &manifest{}
+	var files []file
+
+	for i, p := range paths {
+		entry := manifestPath{Pattern: p.Pattern}
+
+		root, err := resolve(p.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		info, err := os.Stat(root)
+		if errors.Is(err, os.ErrNotExist) {
+			m.Paths = append(m.Paths, entry)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		entry.Exists = true
+		entry.Directory = info.IsDir()
+		m.Paths = append(m.Paths, entry)
+
+		prefix := strconv.Itoa(i)
+		if !info.IsDir() {
+			files = append(files, file{name: prefix, path: root, mode: info.Mode()})
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, file{name: prefix + "/" + filepath.ToSlash(rel), path: path, mode: info.Mode()})
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return m, files, nil
+}
+
+// resolve returns the real location of a save path, since save folders inside of a wine prefix are often symlinks to the
+// real home directory. Saves are snapshotted from and restored to the same location, so a link is never replaced by a folder.
+func resolve(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return real, err
+	}
+
+	// a link to a folder that doesn't exist yet
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		return resolve(target)
+	}
+
+	// the path doesn't exist, but its parents might be links
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	real, err = resolve(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(path)), nil
+}
+
+// Snapshot archives the current saves of a client game. No snapshot is created if the saves didn't change since the last one,
+// in which case the last snapshot is returned.
+func (m *Manager) Snapshot(game *core.Record, reason string) (*core.Record, error) {
+	paths, err := m.Paths(game)
+	if err != nil {
+		return nil, err
+	}
+	return m.snapshot(game.GetString("game"), paths, reason, m.retention())
+}
+
+func (m *Manager) snapshot(game string, paths []Path, reason string, retention int) (*core.Record, error) {
+	if len(paths) == 0 {
+		return nil, ErrNoSavePaths
+	}
+
+	manifest, files, err := collect(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := m.archivePath(game)
+	if err != nil {
+		return nil, err
+	}
+	hash, size, err := writeSnapshot(path, manifest, files)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	last, err := m.Latest(game)
+	if err == nil && last.GetString("hash") == hash {
+		return last, os.Remove(path)
+	}
+
+	return m.add(game, path, hash, size, len(files), reason, retention)
+}
+
+// add records the snapshot archive at path and prunes the older snapshots of the game.
+func (m *Manager) add(game, path, hash string, size int64, files int, reason string, retention int) (*core.Record, error) {
+	record := core.NewRecord(m.collection)
+	record.Set("game", game)
+	record.Set("file", path)
+	record.Set("hash", hash)
+	record.Set("size", size)
+	record.Set("files", files)
+	record.Set("reason", reason)
+	if err := m.app.Save(record); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if err := m.prune(game, retention); err != nil {
+		m.app.Logger().Error("failed to prune snapshots", "game", game, "error", err)
+	}
+
+	return record, nil
+}
+
+// archivePath returns a new path for a snapshot archive of a game.
+func (m *Manager) archivePath(game string) (string, error) {
+	directory := filepath.Join(m.directory, game)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+	name := time.Now().Format("20060102-150405.000")
+	path := filepath.Join(directory, name+".zip")
+	// a restore takes a backup right after the snapshot being restored was added
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		}
+		path = filepath.Join(directory, fmt.Sprintf("%s-%d.zip", name, i))
+	}
+}
+
+// writeSnapshot writes the files into a zip archive and returns the hash of their names and contents.
+func writeSnapshot(path string, manifest *manifest, files []file) (string, int64, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	hash := sha256.New()
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return "", 0, err
+	}
+	hash.Write(manifestData)
+	mw, err := w.Create(manifestName)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := mw.Write(manifestData); err != nil {
+		return "", 0, err
+	}
+
+	for _, f := range files {
+		header := &zip.FileHeader{Name: "files/" + f.name, Method: zip.Deflate}
+		header.SetMode(f.mode)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return "", 0, err
+		}
+
+		src, err := os.Open(f.path)
+		if err != nil {
+			return "", 0, err
+		}
+		fmt.Fprintf(hash, "\x00%s\x00", f.name)
+		_, err = io.Copy(io.MultiWriter(fw, hash), src)
+		src.Close()
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", 0, err
+	}
+	info, err := out.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), info.Size(), out.Close()
+}
+
+// newestFirst sorts snapshots taken within the same millisecond, e. g. the backup of a restore, by insertion as well.
+const newestFirst = "-created,-@rowid"
+
+// Latest returns the newest snapshot of a game.
+func (m *Manager) Latest(game string) (*core.Record, error) {
+	records, err := m.app.FindRecordsByFilter(m.collection, "game = {:game}", newestFirst, 1, 0, dbx.Params{"game": game})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("game %s has no snapshots", game)
+	}
+	return records[0], nil
+}
+
+// prune removes the oldest snapshots of a game, so at most retention are kept.
+func (m *Manager) prune(game string, retention int) error {
+	records, err := m.app.FindRecordsByFilter(m.collection, "game = {:game}", newestFirst, 0, retention, dbx.Params{"game": game})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := m.Delete(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a snapshot and its archive.
+func (m *Manager) Delete(record *core.Record) error {
+	if err := os.Remove(record.GetString("file")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return m.app.Delete(record)
+}
+
+// Restore replaces the saves of a client game with a snapshot. The current saves are snapshotted first, so a restore can be undone.
+func (m *Manager) Restore(snapshot *core.Record, game *core.Record) error {
+	if snapshot.GetString("game") != game.GetString("game") {
+		return fmt.Errorf("snapshot %s doesn't belong to the game", snapshot.Id)
+	}
+
+	paths, err := m.Paths(game)
+	if err != nil {
+		return err
+	}
+	// one more is kept, so the snapshot being restored can't be pruned
+	if _, err := m.snapshot(game.GetString("game"), paths, "restore", m.retention()+1); err != nil && !errors.Is(err, ErrNoSavePaths) {
+		return fmt.Errorf("failed to back up current saves: %w", err)
+	}
+
+	r, err := zip.OpenReader(snapshot.GetString("file"))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	manifest, err := readManifest(&r.Reader)
+	if err != nil {
+		return err
+	}
+
+	// the snapshot might have been taken with different save paths
+	targets := make(map[string]string)
+	for i, entry := range manifest.Paths {
+		for _, p := range paths {
+			if p.Pattern != entry.Pattern {
+				continue
+			}
+			target, err := resolve(p.Path)
+			if err != nil {
+				return err
+			}
+			targets[strconv.Itoa(i)] = target
+		}
+	}
+
+	for i, entry := range manifest.Paths {
+		target, ok := targets[strconv.Itoa(i)]
+		if !ok {
+			continue
+		}
+		// paths that didn't exist yet are removed too, the backup above still has them
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		if entry.Exists && entry.Directory {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		}
+	}
+
+	limiter := archive.NewLimiter(restoreLimits, 0)
+	for _, f := range r.File {
+		if err := limiter.File(f.Name); err != nil {
+			return err
+		}
+		name, ok := strings.CutPrefix(f.Name, "files/")
+		if !ok {
+			continue
+		}
+		index, rel, _ := strings.Cut(name, "/")
+		target, ok := targets[index]
+		if !ok {
+			continue
+		}
+
+		dest := target
+		if rel != "" {
+			dest = filepath.Join(target, filepath.FromSlash(rel))
+			if !strings.HasPrefix(dest, filepath.Clean(target)+string(filepath.Separator)) {
+				return fmt.Errorf("illegal path in snapshot: %s", f.Name)
+			}
+		}
+		if err := restoreFile(f, dest, limiter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readManifest(r *zip.Reader) (*manifest, error) {
+	f, err := r.Open(manifestName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m manifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func restoreFile(f *zip.File, dest string, limiter *archive.Limiter) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	mode := f.Mode().Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	dst, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := archive.CopyBufferWithProgress(context.Background(), dst, src, nil, limiter, nil); err != nil {
+		return err
+	}
+	return dst.Close()
+}
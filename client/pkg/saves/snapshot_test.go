@@ -0,0 +1,162 @@
+package saves_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pocketbase/dbx"
+)
+
+// link replaces the save folder with a link to a folder elsewhere, like the save folders inside of a wine prefix,
+// and returns the real folder.
+func (m *machine) link(t *testing.T) string {
+	t.Helper()
+	real := t.TempDir()
+	if err := os.Symlink(real, filepath.Dir(m.save)); err != nil {
+		t.Fatal(err)
+	}
+	return real
+}
+
+func (m *machine) snapshot(t *testing.T) string {
+	t.Helper()
+	snapshot, err := m.manager.Snapshot(m.game, "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot.Id
+}
+
+func TestSnapshot(t *testing.T) {
+	m := newMachine(t)
+
+	m.write(t, "level 1")
+	first := m.snapshot(t)
+	if again := m.snapshot(t); again != first {
+		t.Errorf("expected unchanged saves to return snapshot %s, got %s", first, again)
+	}
+
+	m.write(t, "level 2")
+	if second := m.snapshot(t); second == first {
+		t.Errorf("expected changed saves to create a new snapshot")
+	}
+
+	snapshots, err := m.app.FindAllRecords("snapshots", dbx.HashExp{"game": "g1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+	for _, snapshot := range snapshots {
+		if _, err := os.Stat(snapshot.GetString("file")); err != nil {
+			t.Errorf("expected the archive of snapshot %s to exist: %v", snapshot.Id, err)
+		}
+		if snapshot.GetInt("files") != 1 {
+			t.Errorf("expected snapshot %s to contain 1 file, got %d", snapshot.Id, snapshot.GetInt("files"))
+		}
+	}
+}
+
+func TestSnapshotLinked(t *testing.T) {
+	m := newMachine(t)
+	m.link(t)
+
+	m.write(t, "level 1")
+	snapshot, err := m.manager.Snapshot(m.game, "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.GetInt("files") != 1 {
+		t.Errorf("expected the saves behind the link to be snapshotted, got %d files", snapshot.GetInt("files"))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := newMachine(t)
+	if err := m.settings.Set("saveRetention", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var archives []string
+	for _, content := range []string{"level 1", "level 2", "level 3", "level 4"} {
+		m.write(t, content)
+		snapshot, err := m.manager.Snapshot(m.game, "manual")
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, snapshot.GetString("file"))
+	}
+
+	snapshots, err := m.app.FindAllRecords("snapshots", dbx.HashExp{"game": "g1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots to be kept, got %d", len(snapshots))
+	}
+	for i, archive := range archives {
+		_, err := os.Stat(archive)
+		if kept := i >= 2; kept != (err == nil) {
+			t.Errorf("expected the archive of snapshot %d to be kept: %v, got %v", i+1, kept, err)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	for _, linked := range []bool{false, true} {
+		name := "folder"
+		if linked {
+			name = "link"
+		}
+		t.Run(name, func(t *testing.T) {
+			m := newMachine(t)
+			folder := filepath.Dir(m.save)
+			real := folder
+			if linked {
+				real = m.link(t)
+			}
+
+			m.write(t, "level 1")
+			old, err := m.manager.Snapshot(m.game, "manual")
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.write(t, "level 2")
+			extra := filepath.Join(folder, "slot2.sav")
+			if err := os.WriteFile(extra, []byte("new game"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.manager.Restore(old, m.game); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(filepath.Join(real, "slot1.sav"))
+			if err != nil || string(content) != "level 1" {
+				t.Errorf("expected the snapshot to be restored into %s, got %q: %v", real, content, err)
+			}
+			if _, err := os.Stat(extra); !os.IsNotExist(err) {
+				t.Errorf("expected files newer than the snapshot to be removed, got %v", err)
+			}
+			if info, err := os.Lstat(folder); err != nil || (info.Mode()&os.ModeSymlink != 0) != linked {
+				t.Errorf("expected the save folder to stay a link: %v, got %v", linked, err)
+			}
+
+			// the saves before the restore are backed up and can be restored again
+			backup, err := m.manager.Latest("g1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backup.GetString("reason") != "restore" {
+				t.Fatalf("expected a backup before restoring, got a %q snapshot", backup.GetString("reason"))
+			}
+			if err := m.manager.Restore(backup, m.game); err != nil {
+				t.Fatal(err)
+			}
+			if content := m.read(t); content != "level 2" {
+				t.Errorf("expected the backup to be restored, got %q", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package saves
+
+import (
+	"archive/zip"
+	"boyl/client/pkg/remote"
+	"boyl/pkg/archive"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Resolution decides which side wins when the saves of a game changed both locally and on the server.
+type Resolution string
+
+const (
+	ResolveNone Resolution = ""
+	KeepLocal   Resolution = "local"
+	KeepRemote  Resolution = "remote"
+)
+
+// MaxArchiveSize is the largest saves archive downloaded from the server, the same the server accepts.
+const MaxArchiveSize = 1 << 30
+
+// ErrConflict is returned by Sync when the saves changed both locally and on the server since the last sync.
+// The game is flagged with saveConflict until the conflict is resolved.
+var ErrConflict = errors.New("saves changed both locally and on the server")
+
+// Sync reconciles the saves of a client game with the server. Local changes are uploaded and changes from other machines
+// are restored, after backing up the local saves. If both changed, nothing is overwritten unless a resolution is given.
+func (m *Manager) Sync(ctx context.Context, r *remote.Client, game *core.Record, resolution Resolution) error {
+	paths, err := m.Paths(game)
+	if err != nil {
+		return err
+	}
+	id := game.GetString("game")
+
+	local, err := m.snapshot(id, paths, "sync", m.retention())
+	if err != nil {
+		return err
+	}
+	hash := local.GetString("hash")
+
+	latest, err := r.LatestSave(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	base := game.GetInt("saveVersion")
+	// a fresh install has no saves, which doesn't count as a change
+	localChanged := hash != game.GetString("saveHash") && local.GetInt("files") > 0
+	remoteChanged := latest != nil && latest.Version != base
+
+	switch {
+	case latest != nil && latest.Hash == hash:
+		return m.setSynced(game, latest.Version, hash)
+	case resolution == KeepRemote:
+		if latest == nil {
+			return errors.New("there are no saves on the server")
+		}
+		return m.download(ctx, r, game, latest)
+	case resolution == KeepLocal:
+		return m.upload(ctx, r, game, local, true)
+	case localChanged && remoteChanged:
+		return m.setConflict(game)
+	case remoteChanged:
+		return m.download(ctx, r, game, latest)
+	case localChanged:
+		return m.upload(ctx, r, game, local, false)
+	}
+
+	return nil
+}
+
+func (m *Manager) upload(ctx context.Context, r *remote.Client, game *core.Record, snapshot *core.Record, force bool) error {
+	machine, _ := os.Hostname()
+	save, err := r.UploadSave(
+		ctx,
+		game.GetString("game"),
+		game.GetInt("saveVersion"),
+		snapshot.GetString("hash"),
+		machine,
+		snapshot.GetString("file"),
+		force,
+	)
+	// another machine uploaded after the latest version was checked
+	if errors.Is(err, remote.ErrSaveConflict) {
+		return m.setConflict(game)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload saves: %w", err)
+	}
+
+	return m.setSynced(game, save.Version, save.Hash)
+}
+
+func (m *Manager) download(ctx context.Context, r *remote.Client, game *core.Record, save *remote.Save) error {
+	if save.Size > MaxArchiveSize {
+		return fmt.Errorf("saves archive of %d bytes exceeds the limit of %d bytes", save.Size, MaxArchiveSize)
+	}
+
+	id := game.GetString("game")
+	path, err := m.archivePath(id)
+	if err != nil {
+		return err
+	}
+
+	if err := downloadArchive(ctx, r, save.ID, path); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to download saves: %w", err)
+	}
+
+	files, err := countFiles(path)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("invalid saves archive: %w", err)
+	}
+
+	// kept as a snapshot, so the saves of the other machine can be restored again later
+	snapshot, err := m.add(id, path, save.Hash, save.Size, files, "sync", m.retention()+1)
+	if err != nil {
+		return err
+	}
+	if err := m.Restore(snapshot, game); err != nil {
+		return err
+	}
+
+	return m.setSynced(game, save.Version, save.Hash)
+}
+
+func downloadArchive(ctx context.Context, r *remote.Client, id, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the size of the save is only what the server claims
+	w := &limitedWriter{w: file, limiter: archive.NewLimiter(archive.Limits{MaxTotalBytes: MaxArchiveSize}, 0)}
+	if err := r.DownloadSave(ctx, id, w); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// limitedWriter fails once more bytes are written than the limiter allows.
+type limitedWriter struct {
+	w       io.Writer
+	limiter *archive.Limiter
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if err := w.limiter.Write(uint64(len(p))); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+func countFiles(path string) (int, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	if _, err := readManifest(&r.Reader); err != nil {
+		return 0, err
+	}
+
+	files := 0
+	for _, f := range r.File {
+		if strings.HasPrefix(f.Name, "files/") {
+			files++
+		}
+	}
+	return files, nil
+}
+
+func (m *Manager) setSynced(game *core.Record, version int, hash string) error {
+	game.Set("saveVersion", version)
+	game.Set("saveHash", hash)
+	game.Set("saveConflict", false)
+	return m.app.Save(game)
+}
+
+func (m *Manager) setConflict(game *core.Record) error {
+	game.Set("saveConflict", true)
+	if err := m.app.Save(game); err != nil {
+		return err
+	}
+	return ErrConflict
+}
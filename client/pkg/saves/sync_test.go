@@ -0,0 +1,266 @@
+package saves_test
+
+import (
+	"boyl/client/pkg/remote"
+	"boyl/client/pkg/saves"
+	"boyl/client/pkg/settings"
+	"boyl/pkg/testapp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	_ "boyl/client/migrations"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// saveServer keeps the versions of the saves of a single game like the server does.
+type saveServer struct {
+	mu       sync.Mutex
+	versions []remote.Save
+	archives map[string][]byte
+}
+
+func newSaveServer(t *testing.T) (*saveServer, *remote.Client) {
+	s := &saveServer{archives: make(map[string][]byte)}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, remote.New(ts.URL)
+}
+
+func (s *saveServer) latest() *remote.Save {
+	if len(s.versions) == 0 {
+		return nil
+	}
+	return &s.versions[len(s.versions)-1]
+}
+
+func (s *saveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/saves/latest":
+		if latest := s.latest(); latest != nil {
+			json.NewEncoder(w).Encode(latest)
+			return
+		}
+		http.Error(w, `{"message":"no saves"}`, http.StatusNotFound)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/saves/download":
+		w.Write(s.archives[query.Get("id")])
+	case r.Method == http.MethodPost && r.URL.Path == "/api/saves":
+		file, _, err := r.FormFile("archive")
+		if err != nil {
+			http.Error(w, `{"message":"no archive"}`, http.StatusBadRequest)
+			return
+		}
+		archive, _ := io.ReadAll(file)
+
+		base, _ := strconv.Atoi(query.Get("base"))
+		latest := s.latest()
+		if latest != nil && latest.Version != base && query.Get("force") != "true" {
+			http.Error(w, `{"message":"conflict"}`, http.StatusConflict)
+			return
+		}
+		save := remote.Save{
+			ID:      "v" + strconv.Itoa(len(s.versions)+1),
+			Game:    query.Get("game"),
+			Version: len(s.versions) + 1,
+			Hash:    query.Get("hash"),
+			Machine: query.Get("machine"),
+			Size:    int64(len(archive)),
+		}
+		s.versions = append(s.versions, save)
+		s.archives[save.ID] = archive
+		json.NewEncoder(w).Encode(save)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// machine is a client with the game installed.
+type machine struct {
+	app      core.App
+	settings *settings.Settings
+	manager  *saves.Manager
+	game     *core.Record
+	save     string
+}
+
+func newMachine(t *testing.T) *machine {
+	t.Helper()
+	app := testapp.New(t)
+
+	settingsCollection, err := app.FindCollectionByNameOrId("settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := app.FindCollectionByNameOrId("snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	games, err := app.FindCollectionByNameOrId("games")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	install := t.TempDir()
+	game := core.NewRecord(games)
+	game.Set("game", "g1")
+	game.Set("path", install)
+	game.Set("savePaths", []string{"{install}/saves"})
+	if err := app.Save(game); err != nil {
+		t.Fatal(err)
+	}
+
+	s := settings.NewSettings(app, settingsCollection)
+	manager := saves.NewManager(app, snapshots, s, t.TempDir())
+	return &machine{app: app, settings: s, manager: manager, game: game, save: filepath.Join(install, "saves", "slot1.sav")}
+}
+
+func (m *machine) write(t *testing.T, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(m.save), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(m.save, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (m *machine) read(t *testing.T) string {
+	t.Helper()
+	content, err := os.ReadFile(m.save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func (m *machine) sync(t *testing.T, r *remote.Client, resolution saves.Resolution) error {
+	t.Helper()
+	err := m.manager.Sync(context.Background(), r, m.game, resolution)
+	if err != nil && !errors.Is(err, saves.ErrConflict) {
+		t.Fatal(err)
+	}
+	return err
+}
+
+func TestSync(t *testing.T) {
+	server, r := newSaveServer(t)
+	desktop := newMachine(t)
+	laptop := newMachine(t)
+
+	// a fresh install without saves and nothing on the server
+	if err := laptop.sync(t, r, saves.ResolveNone); err != nil || len(server.versions) != 0 {
+		t.Fatalf("expected nothing to sync, got %v and %d versions", err, len(server.versions))
+	}
+
+	// a local change is uploaded
+	desktop.write(t, "level 1")
+	desktop.sync(t, r, saves.ResolveNone)
+	if len(server.versions) != 1 || desktop.game.GetInt("saveVersion") != 1 {
+		t.Fatalf("expected the saves to be uploaded as version 1, got %d versions", len(server.versions))
+	}
+
+	// nothing changed since, the hash is identical
+	desktop.sync(t, r, saves.ResolveNone)
+	if len(server.versions) != 1 {
+		t.Errorf("expected identical saves not to be uploaded again, got %d versions", len(server.versions))
+	}
+
+	// a fresh install without saves gets them from the server, a remote change
+	laptop.sync(t, r, saves.ResolveNone)
+	if content := laptop.read(t); content != "level 1" {
+		t.Errorf("expected the saves of the server, got %q", content)
+	}
+	if laptop.game.GetInt("saveVersion") != 1 {
+		t.Errorf("expected the laptop to be synced to version 1, got %d", laptop.game.GetInt("saveVersion"))
+	}
+
+	// both changed, nothing is overwritten
+	desktop.write(t, "level 2")
+	desktop.sync(t, r, saves.ResolveNone)
+	laptop.write(t, "level 1 side quest")
+	if err := laptop.sync(t, r, saves.ResolveNone); !errors.Is(err, saves.ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if !laptop.game.GetBool("saveConflict") || laptop.read(t) != "level 1 side quest" || len(server.versions) != 2 {
+		t.Fatalf("expected the conflict to be flagged without overwriting anything")
+	}
+
+	// the conflict is resolved by keeping the saves of the server
+	laptop.sync(t, r, saves.KeepRemote)
+	if content := laptop.read(t); content != "level 2" || laptop.game.GetBool("saveConflict") {
+		t.Errorf("expected the saves of the server without a conflict, got %q", content)
+	}
+
+	// or by keeping the local saves, which are uploaded even though they aren't based on the latest version
+	desktop.write(t, "level 3")
+	desktop.sync(t, r, saves.ResolveNone)
+	laptop.write(t, "level 2 side quest")
+	if err := laptop.sync(t, r, saves.ResolveNone); !errors.Is(err, saves.ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	laptop.sync(t, r, saves.KeepLocal)
+	if latest := server.latest(); latest.Version != 4 || laptop.game.GetInt("saveVersion") != 4 || laptop.game.GetBool("saveConflict") {
+		t.Errorf("expected the local saves to be uploaded as version 4, got version %d", latest.Version)
+	}
+
+	// the desktop only changed remotely now
+	desktop.sync(t, r, saves.ResolveNone)
+	if content := desktop.read(t); content != "level 2 side quest" {
+		t.Errorf("expected the saves of the laptop, got %q", content)
+	}
+}
+
+func TestSyncLinked(t *testing.T) {
+	server, r := newSaveServer(t)
+	desktop := newMachine(t)
+	laptop := newMachine(t)
+	real := laptop.link(t)
+
+	desktop.write(t, "level 1")
+	desktop.sync(t, r, saves.ResolveNone)
+	laptop.sync(t, r, saves.ResolveNone)
+	if len(server.versions) != 1 {
+		t.Fatalf("expected 1 version on the server, got %d", len(server.versions))
+	}
+
+	content, err := os.ReadFile(filepath.Join(real, "slot1.sav"))
+	if err != nil || string(content) != "level 1" {
+		t.Errorf("expected the saves of the server in the real save folder, got %q: %v", content, err)
+	}
+	if info, err := os.Lstat(filepath.Dir(laptop.save)); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the save folder to stay a link, got %v", err)
+	}
+}
+
+func TestSyncTooLarge(t *testing.T) {
+	server, r := newSaveServer(t)
+	desktop := newMachine(t)
+	laptop := newMachine(t)
+
+	desktop.write(t, "level 1")
+	desktop.sync(t, r, saves.ResolveNone)
+	server.versions[0].Size = saves.MaxArchiveSize + 1
+
+	if err := laptop.manager.Sync(context.Background(), r, laptop.game, saves.ResolveNone); err == nil {
+		t.Fatal("expected an archive above the limit to be rejected")
+	}
+	if _, err := os.Stat(laptop.save); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be restored, got %v", err)
+	}
+	if laptop.game.GetInt("saveVersion") != 0 {
+		t.Errorf("expected the laptop not to be synced, got version %d", laptop.game.GetInt("saveVersion"))
+	}
+}
@@ -0,0 +1,358 @@
+package servers
+
+import (
+	"boyl/client/pkg/credentials"
+	"boyl/client/pkg/remote"
+	"boyl/client/pkg/settings"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var ErrNotFound = errors.New("server not found")
+
+// Registry keeps the server profiles of the client in the servers collection, with a remote client for each of them,
+// so games can be installed from more than one server.
+type Registry struct {
+	app           core.App
+	collection    *core.Collection
+	settings      *settings.Settings
+	dataDirectory string
+
+	mu      sync.Mutex
+	clients map[string]*remote.Client
+}
+
+// NewRegistry returns a Registry, which stores the tokens of the servers next to the database in dataDirectory or in the keyring.
+func NewRegistry(app core.App, collection *core.Collection, settings *settings.Settings, dataDirectory string) *Registry {
+	return &Registry{
+		app:           app,
+		collection:    collection,
+		settings:      settings,
+		dataDirectory: dataDirectory,
+		mu:            sync.Mutex{},
+		clients:       make(map[string]*remote.Client),
+	}
+}
+
+// List returns the server profiles sorted by name.
+func (r *Registry) List() ([]*core.Record, error) {
+	return r.app.FindRecordsByFilter(r.collection, "", "name", 0, 0)
+}
+
+// Default returns the profile used when none is picked, the one of the defaultServer setting or else the first one.
+func (r *Registry) Default() (*core.Record, error) {
+	if id := r.settings.GetString("defaultServer"); id != "" {
+		if server, err := r.app.FindRecordById(r.collection, id); err == nil {
+			return server, nil
+		}
+	}
+
+	servers, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, ErrNotFound
+	}
+	return servers[0], nil
+}
+
+// Find returns the profile with the id or name ref, the default one if ref is empty.
+func (r *Registry) Find(ref string) (*core.Record, error) {
+	if ref == "" {
+		return r.Default()
+	}
+	if server, err := r.app.FindRecordById(r.collection, ref); err == nil {
+		return server, nil
+	}
+	server, err := r.app.FindFirstRecordByData(r.collection, "name", ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, ref)
+	}
+	return server, nil
+}
+
+// Client returns the remote client of a profile, see Find. Everything talking to a server shares its client,
+// so the token is only renewed once.
+func (r *Registry) Client(ref string) (*remote.Client, error) {
+	server, err := r.Find(ref)
+	if err != nil {
+		return nil, err
+	}
+	return r.client(server), nil
+}
+
+// ForGame returns the remote client of the server an installed game or a download came from.
+func (r *Registry) ForGame(record *core.Record) (*remote.Client, error) {
+	return r.Client(record.GetString("server"))
+}
+
+func (r *Registry) client(server *core.Record) *remote.Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[server.Id]; ok && client.URL == server.GetString("url") {
+		return client
+	}
+	client := r.newClient(server.GetString("url"))
+	r.watch(server.Id, client)
+	r.clients[server.Id] = client
+	return client
+}
+
+func (r *Registry) newClient(serverURL string) *remote.Client {
+	client := remote.New(serverURL)
+	if timeout := r.settings.GetNumber("serverTimeout"); timeout > 0 {
+		client.Timeout = time.Duration(timeout * float64(time.Second))
+	}
+	return client
+}
+
+// watch stores every new token of the client of a server.
+func (r *Registry) watch(id string, client *remote.Client) {
+	store := credentials.New(r.dataDirectory, id)
+	client.OnToken(func(token string) {
+		if err := store.Set(token); err != nil {
+			r.app.Logger().Error("failed to store token", "server", id, "error", err)
+		}
+	})
+}
+
+// Authenticate continues the sessions of all profiles with their stored tokens. Errors of single servers are returned together,
+// the other servers can be used anyway.
+func (r *Registry) Authenticate(ctx context.Context) error {
+	var errs []error
+	if err := r.migratePassword(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	servers, err := r.List()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, server := range servers {
+		if err := r.authenticate(ctx, server); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", server.GetString("name"), err))
+		}
+		r.warn(server, r.client(server))
+	}
+	return errors.Join(errs...)
+}
+
+// migratePassword replaces the password stored by earlier versions with a token. The password is removed from the settings
+// first, so it isn't kept in plain text if the server can't be reached, in which case the user has to log in again.
+func (r *Registry) migratePassword(ctx context.Context) error {
+	password := r.settings.GetString("password")
+	if password == "" {
+		return nil
+	}
+	if err := r.settings.Delete("password"); err != nil {
+		return err
+	}
+
+	server, err := r.Default()
+	if err != nil {
+		return fmt.Errorf("failed to log in with the stored password: %w", err)
+	}
+	if err := r.client(server).Authenticate(ctx, server.GetString("email"), password); err != nil {
+		return fmt.Errorf("%s: failed to log in with the stored password, please log in again: %w", server.GetString("name"), err)
+	}
+	return nil
+}
+
+// warn logs what doesn't work with a server, e. g. features an older server doesn't have.
+func (r *Registry) warn(server *core.Record, client *remote.Client) {
+	if info := client.Info(); info != nil && remote.Warning(info) != "" {
+		r.app.Logger().Warn("server is only partly supported", "server", server.GetString("name"), "warning", remote.Warning(info))
+	}
+}
+
+func (r *Registry) authenticate(ctx context.Context, server *core.Record) error {
+	client := r.client(server)
+	if client.Identity() != "" {
+		return nil
+	}
+	token, err := credentials.New(r.dataDirectory, server.Id).Get()
+	if err != nil || token == "" {
+		return err
+	}
+	return client.Resume(ctx, server.GetString("email"), token)
+}
+
+// Login logs into a server with the password of the user and saves the profile named name, which is created if it doesn't exist yet.
+// An empty name is derived from the URL. The profile stays unchanged if the login fails, and the first profile becomes the default one.
+func (r *Registry) Login(ctx context.Context, name, serverURL, email, password string) (*core.Record, error) {
+	serverURL = strings.TrimSuffix(serverURL, "/")
+	if name == "" {
+		name = defaultName(serverURL)
+	}
+
+	client := r.newClient(serverURL)
+	if err := client.Authenticate(ctx, email, password); err != nil {
+		return nil, err
+	}
+	return r.save(name, serverURL, email, client)
+}
+
+// Register creates an account on a server with an invite and saves the profile named name, like Login.
+func (r *Registry) Register(ctx context.Context, name, serverURL, invite, email, password string) (*core.Record, error) {
+	serverURL = strings.TrimSuffix(serverURL, "/")
+	if name == "" {
+		name = defaultName(serverURL)
+	}
+
+	client := r.newClient(serverURL)
+	if err := client.Register(ctx, invite, email, password); err != nil {
+		return nil, err
+	}
+	return r.save(name, serverURL, email, client)
+}
+
+// save creates or updates the profile named name for a client that just logged in.
+func (r *Registry) save(name, serverURL, email string, client *remote.Client) (*core.Record, error) {
+	server, err := r.app.FindFirstRecordByData(r.collection, "name", name)
+	if err != nil {
+		server = core.NewRecord(r.collection)
+		server.Set("name", name)
+	}
+	server.Set("url", serverURL)
+	server.Set("email", email)
+	if err := r.app.Save(server); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	// a replaced client must not overwrite the new token when it renews its own
+	if previous, ok := r.clients[server.Id]; ok {
+		previous.OnToken(nil)
+	}
+	r.watch(server.Id, client)
+	r.clients[server.Id] = client
+	r.mu.Unlock()
+	if err := credentials.New(r.dataDirectory, server.Id).Set(client.Token()); err != nil {
+		return nil, err
+	}
+	r.warn(server, client)
+
+	if r.settings.GetString("defaultServer") == "" {
+		if err := r.settings.Set("defaultServer", server.Id); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
+
+// Logout forgets the token of the user for a profile, see Find.
+func (r *Registry) Logout(ref string) error {
+	client, err := r.Client(ref)
+	if err != nil {
+		return err
+	}
+	client.Logout()
+	return nil
+}
+
+// SetDefault makes a profile the one used when none is picked.
+func (r *Registry) SetDefault(ref string) error {
+	server, err := r.Find(ref)
+	if err != nil {
+		return err
+	}
+	return r.settings.Set("defaultServer", server.Id)
+}
+
+// Remove logs out of a server and deletes its profile. Games installed from it stay playable, but aren't synced anymore.
+func (r *Registry) Remove(ref string) error {
+	server, err := r.Find(ref)
+	if err != nil {
+		return err
+	}
+	r.client(server).Logout()
+
+	r.mu.Lock()
+	delete(r.clients, server.Id)
+	r.mu.Unlock()
+
+	if err := r.app.Delete(server); err != nil {
+		return err
+	}
+	if r.settings.GetString("defaultServer") == server.Id {
+		return r.settings.Delete("defaultServer")
+	}
+	return nil
+}
+
+// Import turns the single server of earlier versions, stored in the serverUrl and email settings, into the default profile.
+// The records of collections without a server, e. g. the installed games, are tagged with it. Nothing happens once it was imported.
+func (r *Registry) Import(collections ...*core.Collection) error {
+	serverURL := r.settings.GetString("serverUrl")
+	if serverURL == "" {
+		return nil
+	}
+
+	// the settings are removed last, so an interrupted import is done again
+	name := defaultName(serverURL)
+	server, err := r.app.FindFirstRecordByData(r.collection, "name", name)
+	if err != nil {
+		server = core.NewRecord(r.collection)
+		server.Set("name", name)
+		server.Set("url", strings.TrimSuffix(serverURL, "/"))
+		server.Set("email", r.settings.GetString("email"))
+		if err := r.app.Save(server); err != nil {
+			return err
+		}
+	}
+
+	for _, collection := range collections {
+		records, err := r.app.FindAllRecords(collection, dbx.HashExp{"server": ""})
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			record.Set("server", server.Id)
+			if err := r.app.Save(record); err != nil {
+				return err
+			}
+		}
+	}
+	if err := r.settings.Set("defaultServer", server.Id); err != nil {
+		return err
+	}
+
+	legacy := credentials.New(r.dataDirectory, "")
+	token, err := legacy.Get()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		// cleared first, since lookups in the keyring would find the token of the server too
+		if err := legacy.Set(""); err != nil {
+			return err
+		}
+		if err := credentials.New(r.dataDirectory, server.Id).Set(token); err != nil {
+			return err
+		}
+	}
+
+	if err := r.settings.Delete("serverUrl"); err != nil {
+		return err
+	}
+	return r.settings.Delete("email")
+}
+
+// defaultName names a profile after the host of its server.
+func defaultName(serverURL string) string {
+	parsed, err := url.Parse(serverURL)
+	if err != nil || parsed.Host == "" {
+		return serverURL
+	}
+	return parsed.Host
+}
@@ -0,0 +1,296 @@
+package servers_test
+
+import (
+	"boyl/client/pkg/credentials"
+	"boyl/client/pkg/remote"
+	"boyl/client/pkg/servers"
+	"boyl/client/pkg/settings"
+	"boyl/pkg/api"
+	"boyl/pkg/testapp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "boyl/client/migrations"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// newServer starts a fake server, which accepts the password "secret" and hands out a new token for every login.
+func newServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	logins := 0
+	respond := func(w http.ResponseWriter, token string) {
+		json.NewEncoder(w).Encode(map[string]any{"token": token, "record": map[string]string{"email": "user@example.com"}})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/info", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.Info{Version: "test", Protocol: api.Protocol, MinProtocol: api.MinProtocol})
+	})
+	mux.HandleFunc("POST /api/collections/users/auth-with-password", func(w http.ResponseWriter, r *http.Request) {
+		var body remote.AuthRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Password != "secret" {
+			http.Error(w, `{"message":"Failed to authenticate."}`, http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		logins++
+		name := fmt.Sprintf("login%d", logins)
+		mu.Unlock()
+		respond(w, token(name))
+	})
+	mux.HandleFunc("POST /api/collections/users/auth-refresh", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	})
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func token(name string) string {
+	payload, _ := json.Marshal(map[string]any{"id": name, "exp": time.Now().Add(time.Hour).Unix()})
+	return "header." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func setup(t *testing.T) (core.App, *servers.Registry, *settings.Settings, string) {
+	t.Helper()
+	app := testapp.New(t)
+
+	settingsCollection, err := app.FindCollectionByNameOrId("settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := app.FindCollectionByNameOrId("servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := settings.NewSettings(app, settingsCollection)
+	dataDirectory := t.TempDir()
+	return app, servers.NewRegistry(app, collection, s, dataDirectory), s, dataDirectory
+}
+
+func TestFind(t *testing.T) {
+	_, registry, _, _ := setup(t)
+	ts := newServer(t)
+
+	home, err := registry.Login(context.Background(), "home", ts.URL, "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := registry.Login(context.Background(), "", ts.URL+"/", "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := strings.TrimPrefix(ts.URL, "http://"); other.GetString("name") != host || other.GetString("url") != ts.URL {
+		t.Errorf("expected the profile to be named after %q, got %q for %q", host, other.GetString("name"), other.GetString("url"))
+	}
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected string
+	}{
+		{"id", other.Id, other.Id},
+		{"name", "home", home.Id},
+		{"default", "", home.Id},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, err := registry.Find(test.ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if server.Id != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, server.Id)
+			}
+		})
+	}
+
+	if _, err := registry.Find("missing"); !errors.Is(err, servers.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	_, registry, s, _ := setup(t)
+	ts := newServer(t)
+
+	home, err := registry.Login(context.Background(), "home", ts.URL, "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := registry.Login(context.Background(), "other", ts.URL, "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetString("defaultServer") != home.Id {
+		t.Fatalf("expected the first profile to be the default one, got %q", s.GetString("defaultServer"))
+	}
+
+	if err := registry.Remove("home"); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetString("defaultServer") != "" {
+		t.Errorf("expected the default server to be cleared, got %q", s.GetString("defaultServer"))
+	}
+	if server, err := registry.Default(); err != nil || server.Id != other.Id {
+		t.Errorf("expected the remaining profile to be the default one, got %v", err)
+	}
+	if _, err := registry.Find("home"); !errors.Is(err, servers.ErrNotFound) {
+		t.Errorf("expected the profile to be deleted, got %v", err)
+	}
+}
+
+func TestLoginReplacesClient(t *testing.T) {
+	_, registry, _, dataDirectory := setup(t)
+	ts := newServer(t)
+
+	server, err := registry.Login(context.Background(), "home", ts.URL, "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := registry.Client("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := registry.Login(context.Background(), "home", ts.URL, "user@example.com", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Id != server.Id {
+		t.Fatalf("expected the profile to be updated, got a new one")
+	}
+	client, err := registry.Client("home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client == previous || client.Token() == previous.Token() {
+		t.Fatal("expected the client to be replaced")
+	}
+
+	// the token of the replaced client isn't stored anymore
+	previous.Logout()
+	stored, err := credentials.New(dataDirectory, server.Id).Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != client.Token() {
+		t.Errorf("expected the token of the new client to be stored, got %q", stored)
+	}
+}
+
+func TestImport(t *testing.T) {
+	app, registry, s, dataDirectory := setup(t)
+
+	games, err := app.FindCollectionByNameOrId("games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := core.NewRecord(games)
+	game.Set("game", "g1")
+	if err := app.Save(game); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{"serverUrl": "http://nas:8090/", "email": "user@example.com"} {
+		if err := s.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := credentials.New(dataDirectory, "").Set("legacy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// importing again, e. g. after an interrupted import, doesn't create a second profile
+	for range 2 {
+		if err := registry.Import(games); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := registry.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected a single profile, got %d", len(list))
+	}
+	server := list[0]
+	if server.GetString("name") != "nas:8090" || server.GetString("url") != "http://nas:8090" || server.GetString("email") != "user@example.com" {
+		t.Errorf("unexpected profile %q for %q and %q", server.GetString("name"), server.GetString("url"), server.GetString("email"))
+	}
+	if s.GetString("defaultServer") != server.Id || s.GetString("serverUrl") != "" || s.GetString("email") != "" {
+		t.Error("expected the profile to replace the settings")
+	}
+
+	game, err = app.FindRecordById(games, game.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.GetString("server") != server.Id {
+		t.Errorf("expected the game to be tagged with the profile, got %q", game.GetString("server"))
+	}
+	if token, _ := credentials.New(dataDirectory, server.Id).Get(); token != "legacy" {
+		t.Errorf("expected the token to be moved to the profile, got %q", token)
+	}
+}
+
+func TestAuthenticateLegacyPassword(t *testing.T) {
+	app, registry, s, dataDirectory := setup(t)
+	ts := newServer(t)
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	collection, err := app.FindCollectionByNameOrId("servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, name := range []string{"home", "other"} {
+		server := core.NewRecord(collection)
+		server.Set("name", name)
+		server.Set("email", "user@example.com")
+		server.Set("url", ts.URL)
+		if name == "home" {
+			server.Set("url", unreachable.URL)
+		}
+		if err := app.Save(server); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, server.Id)
+	}
+	if err := s.Set("defaultServer", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("password", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.New(dataDirectory, ids[1]).Set(token("stored")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Authenticate(context.Background()); !errors.Is(err, remote.ErrUnavailable) {
+		t.Errorf("expected the default server to be unavailable, got %v", err)
+	}
+	if s.GetString("password") != "" {
+		t.Error("expected the password to be removed")
+	}
+	other, err := registry.Client("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Identity() != "user@example.com" {
+		t.Error("expected the session of the other server to be continued")
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"boyl/client/pkg/download"
+	"boyl/client/pkg/launch"
+	"boyl/client/pkg/saves"
+	"boyl/client/pkg/servers"
+	"boyl/client/pkg/settings"
+	"boyl/pkg/api"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Address is where the client serves the UI and its API.
+const Address = "localhost:48658"
+
+// Local reports whether a request comes from the UI served at Address. Any website can send requests to the client,
+// but browsers add its origin, and the host stops websites pointing a domain of their own at localhost.
+func Local(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return r.Host == Address && (origin == "" || origin == "http://"+Address)
+}
+
+// ErrInvalidLaunch wraps errors of games that can't be started because of how they are set up, e. g. a missing runner.
+var ErrInvalidLaunch = errors.New("invalid launch configuration")
+
+// Services are the parts of the client shared by the UI and the commands.
+type Services struct {
+	App       core.App
+	Settings  *settings.Settings
+	Servers   *servers.Registry
+	Downloads *download.Manager
+	Tracker   *launch.Tracker
+	Saves     *saves.Manager
+
+	DownloadsCollection *core.Collection
+	GamesCollection     *core.Collection
+	ServersCollection   *core.Collection
+	SessionsCollection  *core.Collection
+	SnapshotsCollection *core.Collection
+}
+
+// New sets up the services of a bootstrapped app. The remote clients aren't authenticated yet, see Authenticate.
+func New(app core.App) (*Services, error) {
+	s := &Services{App: app}
+
+	collections := []struct {
+		name       string
+		collection **core.Collection
+	}{
+		{"downloads", &s.DownloadsCollection},
+		{"games", &s.GamesCollection},
+		{"servers", &s.ServersCollection},
+		{"sessions", &s.SessionsCollection},
+		{"snapshots", &s.SnapshotsCollection},
+	}
+	for _, c := range collections {
+		collection, err := app.FindCollectionByNameOrId(c.name)
+		if err != nil {
+			return nil, err
+		}
+		*c.collection = collection
+	}
+	settingsCollection, err := app.FindCollectionByNameOrId("settings")
+	if err != nil {
+		return nil, err
+	}
+
+	s.Settings = settings.NewSettings(app, settingsCollection)
+	s.Servers = servers.NewRegistry(app, s.ServersCollection, s.Settings, app.DataDir())
+	if err := s.Servers.Import(s.GamesCollection, s.DownloadsCollection, s.SessionsCollection); err != nil {
+		return nil, err
+	}
+	s.Downloads = download.NewManager(app, s.DownloadsCollection, s.GamesCollection, s.Settings, s.Servers)
+	s.Tracker = launch.NewTracker(app, s.SessionsCollection, filepath.Join(app.DataDir(), "logs"))
+	s.Saves = saves.NewManager(app, s.SnapshotsCollection, s.Settings, filepath.Join(app.DataDir(), "saves"))
+
+	s.Tracker.OnExit(func(session *core.Record) {
+		game, err := app.FindFirstRecordByData(s.GamesCollection, "game", session.GetString("game"))
+		if err != nil {
+			return
+		}
+		if _, err := s.Saves.Snapshot(game, "session"); err != nil && !errors.Is(err, saves.ErrNoSavePaths) {
+			app.Logger().Error("failed to snapshot saves", "game", game.Id, "error", err)
+		}
+		s.SyncSaves(context.Background(), game)
+	})
+
+	return s, nil
+}
+
+// Authenticate continues the sessions of the server profiles with their stored tokens.
+func (s *Services) Authenticate(ctx context.Context) error {
+	return s.Servers.Authenticate(ctx)
+}
+
+// SyncPlaytime sends the finished sessions to the servers the games came from, if the syncPlaytime setting is enabled.
+func (s *Services) SyncPlaytime(ctx context.Context) {
+	if !s.Settings.GetBool("syncPlaytime") {
+		return
+	}
+	profiles, err := s.Servers.List()
+	if err != nil {
+		s.App.Logger().Error("failed to sync playtime", "error", err)
+		return
+	}
+	for _, profile := range profiles {
+		// sessions of servers without the feature stay unsynced until they are updated
+		r, err := s.Servers.Client(profile.Id)
+		if err != nil || r.Identity() == "" || !r.Supports(api.FeaturePlaytime) {
+			continue
+		}
+		if err := s.Tracker.Sync(ctx, r, profile.Id); err != nil {
+			s.App.Logger().Error("failed to sync playtime", "server", profile.GetString("name"), "error", err)
+		}
+	}
+}
+
+// SyncSaves syncs the saves of a game with the server it came from, if the syncSaves setting is enabled, the client is logged in
+// and the server supports saves.
+func (s *Services) SyncSaves(ctx context.Context, game *core.Record) error {
+	if !s.Settings.GetBool("syncSaves") {
+		return nil
+	}
+	// games of a removed server can't be synced anymore
+	r, err := s.Servers.ForGame(game)
+	if err != nil || r.Identity() == "" || !r.Supports(api.FeatureSaves) {
+		return nil
+	}
+	err = s.Saves.Sync(ctx, r, game, saves.ResolveNone)
+	if errors.Is(err, saves.ErrNoSavePaths) {
+		return nil
+	}
+	if err != nil {
+		s.App.Logger().Error("failed to sync saves", "game", game.Id, "error", err)
+	}
+	return err
+}
+
+// Launch starts a profile of a client game and returns its session.
+func (s *Services) Launch(ctx context.Context, game *core.Record, profileName string) (*core.Record, error) {
+	profile, err := launch.FindProfile(game, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := launch.GameOptions(game, s.Settings, profile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLaunch, err)
+	}
+
+	cmd, err := launch.Command(profile, options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLaunch, err)
+	}
+
+	// the game would otherwise start with outdated saves, a failed sync because of being offline doesn't block it
+	if err := s.SyncSaves(ctx, game); errors.Is(err, saves.ErrConflict) {
+		return nil, err
+	}
+
+	return s.Tracker.Start(game.GetString("game"), game.GetString("server"), profile.Name, cmd, options.Hooks)
+}
@@ -0,0 +1,34 @@
+package services_test
+
+import (
+	"boyl/client/pkg/services"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		want   bool
+	}{
+		{name: "ui", host: services.Address, origin: "http://" + services.Address, want: true},
+		{name: "same origin", host: services.Address, want: true},
+		{name: "other website", host: services.Address, origin: "https://example.com"},
+		{name: "other port", host: services.Address, origin: "http://localhost:5173"},
+		{name: "rebound domain", host: "example.com:48658"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://"+tt.host+"/api/token", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := services.Local(req); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
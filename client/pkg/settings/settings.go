@@ -44,6 +44,59 @@ func (s *Settings) GetString(key string) string {
 	return str
 }
 
+func (s *Settings) GetNumber(key string) float64 {
+	value, err := s.Get(key)
+	if err != nil {
+		return 0
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return 0
+	}
+	return number
+}
+
+func (s *Settings) GetBool(key string) bool {
+	value, err := s.Get(key)
+	if err != nil {
+		return false
+	}
+
+	b, ok := value.(bool)
+	return ok && b
+}
+
+func (s *Settings) GetStrings(key string) []string {
+	value, err := s.Get(key)
+	if err != nil {
+		return nil
+	}
+
+	values, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		if str, ok := v.(string); ok && str != "" {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
+
+// Unmarshal decodes the value of key into v, for values that aren't a plain string or number.
+func (s *Settings) Unmarshal(key string, v any) error {
+	record, err := s.app.FindFirstRecordByData(s.collection, "key", key)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(record.GetString("value")), v)
+}
+
 func (s *Settings) Set(key, value any) error {
 	marshaled, err := json.Marshal(value)
 	if err != nil {
@@ -58,3 +111,12 @@ func (s *Settings) Set(key, value any) error {
 	record.Set("value", string(marshaled))
 	return s.app.Save(record)
 }
+
+// Delete removes a setting, which isn't an error if it doesn't exist.
+func (s *Settings) Delete(key string) error {
+	record, err := s.app.FindFirstRecordByData(s.collection, "key", key)
+	if err != nil {
+		return nil
+	}
+	return s.app.Delete(record)
+}
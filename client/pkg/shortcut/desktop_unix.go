@@ -0,0 +1,81 @@
+//go:build !windows
+
+package shortcut
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IconExtension is the icon format desktop entries support.
+const IconExtension = ".png"
+
+func directories() (string, string) {
+	home, _ := os.UserHomeDir()
+
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		data = filepath.Join(home, ".local", "share")
+	}
+	desktop := os.Getenv("XDG_DESKTOP_DIR")
+	if desktop == "" {
+		desktop = filepath.Join(home, "Desktop")
+	}
+
+	return filepath.Join(data, "applications"), desktop
+}
+
+// write creates a desktop entry, see https://specifications.freedesktop.org/desktop-entry-spec/latest/.
+func write(directory string, s Shortcut) (string, error) {
+	exec := []string{quoteArg(s.Command)}
+	for _, arg := range s.Args {
+		exec = append(exec, quoteArg(arg))
+	}
+
+	var entry strings.Builder
+	entry.WriteString("[Desktop Entry]\n")
+	entry.WriteString("Type=Application\n")
+	entry.WriteString("Version=1.0\n")
+	fmt.Fprintf(&entry, "Name=%s\n", escapeValue(s.Name))
+	fmt.Fprintf(&entry, "Comment=%s\n", escapeValue("Play "+s.Name+" with boyl"))
+	fmt.Fprintf(&entry, "Exec=%s\n", escapeValue(strings.Join(exec, " ")))
+	if s.Icon != "" {
+		fmt.Fprintf(&entry, "Icon=%s\n", escapeValue(s.Icon))
+	}
+	entry.WriteString("Terminal=false\n")
+	entry.WriteString("Categories=Game;\n")
+
+	path := filepath.Join(directory, "boyl-"+s.ID+".desktop")
+	// desktop environments refuse to start entries on the desktop that aren't executable
+	if err := os.WriteFile(path, []byte(entry.String()), 0755); err != nil {
+		return "", err
+	}
+	return path, os.Chmod(path, 0755)
+}
+
+// quoteArg quotes an argument of the Exec key. Percent signs would be taken as field codes.
+func quoteArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for _, r := range arg {
+		if strings.ContainsRune("\"`$\\", r) {
+			quoted.WriteByte('\\')
+		}
+		quoted.WriteRune(r)
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func escapeValue(value string) string {
+	return valueEscaper.Replace(value)
+}
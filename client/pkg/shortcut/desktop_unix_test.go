@@ -0,0 +1,93 @@
+//go:build !windows
+
+package shortcut_test
+
+import (
+	"boyl/client/pkg/shortcut"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateDesktopEntry(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_DESKTOP_DIR", "")
+
+	paths, err := shortcut.Create(shortcut.Shortcut{
+		ID:      "abc",
+		Name:    "Tom & Jerry: 100% Fun",
+		Command: "/opt/boyl/boyl",
+		Args:    []string{"launch", "abc", "--dir", `/home/user/My "Games"/$data`},
+		Icon:    "/home/user/icons/abc.png",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// there is no desktop folder, so only the menu entry is created
+	expectedPath := filepath.Join(home, ".local", "share", "applications", "boyl-abc.desktop")
+	if len(paths) != 1 || paths[0] != expectedPath {
+		t.Fatalf("expected %q, got %q", expectedPath, paths)
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := string(data)
+	for _, line := range []string{
+		"[Desktop Entry]\n",
+		"Name=Tom & Jerry: 100% Fun\n",
+		`Exec=/opt/boyl/boyl launch abc --dir "/home/user/My \\"Games\\"/\\$data"` + "\n",
+		"Icon=/home/user/icons/abc.png\n",
+	} {
+		if !strings.Contains(entry, line) {
+			t.Errorf("expected entry to contain %q, got:\n%s", line, entry)
+		}
+	}
+
+	if err := shortcut.Remove(paths); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("expected shortcut to be removed, got %v", err)
+	}
+	// removing a shortcut twice isn't an error, the user might have deleted it already
+	if err := shortcut.Remove(paths); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateDesktopEntryOnDesktop(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_DESKTOP_DIR", "")
+	if err := os.Mkdir(filepath.Join(home, "Desktop"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := shortcut.Create(shortcut.Shortcut{ID: "abc", Name: "Game", Command: "boyl", Args: []string{"launch", "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(home, "data", "applications", "boyl-abc.desktop"),
+		filepath.Join(home, "Desktop", "boyl-abc.desktop"),
+	}
+	if len(paths) != len(expected) || paths[0] != expected[0] || paths[1] != expected[1] {
+		t.Fatalf("expected %q, got %q", expected, paths)
+	}
+
+	info, err := os.Stat(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected desktop entry to be executable, got %s", info.Mode())
+	}
+}
@@ -0,0 +1,65 @@
+package shortcut
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+// iconSize is the largest size both windows icons and freedesktop icon themes commonly use.
+const iconSize = 256
+
+// WriteIcon turns a cover into a square icon with transparent borders. The format depends on the extension of path,
+// an ICO for ".ico" and a PNG otherwise.
+func WriteIcon(cover io.Reader, path string) error {
+	img, err := imaging.Decode(cover)
+	if err != nil {
+		return err
+	}
+	fitted := imaging.Fit(img, iconSize, iconSize, imaging.Lanczos)
+	icon := imaging.PasteCenter(imaging.New(iconSize, iconSize, color.Transparent), fitted)
+
+	var data bytes.Buffer
+	if err := png.Encode(&data, icon); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".ico") {
+		return os.WriteFile(path, ico(data.Bytes()), 0644)
+	}
+	return os.WriteFile(path, data.Bytes(), 0644)
+}
+
+// ico wraps a 256x256 PNG into an ICO, which windows supports since Vista.
+func ico(data []byte) []byte {
+	const headerSize = 6 + 16
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+		// a width and height of 0 means 256
+		Width, Height, Colors, Reserved2 uint8
+		Planes, BitCount                 uint16
+		Size, Offset                     uint32
+	}{
+		Type:     1,
+		Count:    1,
+		Planes:   1,
+		BitCount: 32,
+		Size:     uint32(len(data)),
+		Offset:   headerSize,
+	})
+	buf.Write(data)
+	return buf.Bytes()
+}
@@ -0,0 +1,82 @@
+package shortcut_test
+
+import (
+	"boyl/client/pkg/shortcut"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func cover(t *testing.T) *bytes.Buffer {
+	// covers are portrait, so the icon gets transparent borders on the sides
+	img := image.NewRGBA(image.Rect(0, 0, 300, 400))
+	for y := range 400 {
+		for x := range 300 {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestWriteIconPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icons", "game.png")
+	if err := shortcut.WriteIcon(cover(t), path); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	icon, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bounds := icon.Bounds(); bounds.Dx() != 256 || bounds.Dy() != 256 {
+		t.Errorf("expected a 256x256 icon, got %v", bounds)
+	}
+	if _, _, _, a := icon.At(0, 128).RGBA(); a != 0 {
+		t.Errorf("expected transparent border, got alpha %d", a)
+	}
+	if r, _, _, a := icon.At(128, 128).RGBA(); a == 0 || r == 0 {
+		t.Errorf("expected the cover in the center, got red %d alpha %d", r, a)
+	}
+}
+
+func TestWriteIconICO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.ico")
+	if err := shortcut.WriteIcon(cover(t), path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 22 {
+		t.Fatalf("icon is too short: %d bytes", len(data))
+	}
+
+	if kind, count := binary.LittleEndian.Uint16(data[2:]), binary.LittleEndian.Uint16(data[4:]); kind != 1 || count != 1 {
+		t.Errorf("expected a single icon, got type %d and %d images", kind, count)
+	}
+	size, offset := binary.LittleEndian.Uint32(data[14:]), binary.LittleEndian.Uint32(data[18:])
+	if int(offset+size) != len(data) {
+		t.Fatalf("image at %d with size %d doesn't match file size %d", offset, size, len(data))
+	}
+	if _, err := png.Decode(bytes.NewReader(data[offset:])); err != nil {
+		t.Errorf("expected an embedded PNG: %v", err)
+	}
+}
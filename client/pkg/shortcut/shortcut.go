@@ -0,0 +1,54 @@
+package shortcut
+
+import (
+	"errors"
+	"os"
+)
+
+// Shortcut starts a game from outside of the client, e. g. the application menu or the desktop.
+type Shortcut struct {
+	// ID identifies the game, shortcuts of the same game replace each other.
+	ID   string
+	Name string
+	// Command is the client binary, which is started with Args.
+	Command string
+	Args    []string
+	// Icon is an image created by WriteIcon, the default icon is used if it's empty.
+	Icon string
+}
+
+// Create writes the shortcut into the application menu and onto the desktop, if there is one, and returns the created files.
+func Create(s Shortcut) ([]string, error) {
+	menu, desktop := directories()
+
+	if err := os.MkdirAll(menu, 0755); err != nil {
+		return nil, err
+	}
+	directories := []string{menu}
+	if info, err := os.Stat(desktop); err == nil && info.IsDir() {
+		directories = append(directories, desktop)
+	}
+
+	var paths []string
+	for _, directory := range directories {
+		path, err := write(directory, s)
+		if err != nil {
+			Remove(paths)
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Remove deletes shortcuts returned by Create. Shortcuts the user already deleted are skipped.
+func Remove(paths []string) error {
+	var errs []error
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package shortcut
+
+import (
+	"boyl/client/pkg/library"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// IconExtension is the icon format shell links support.
+const IconExtension = ".ico"
+
+func directories() (string, string) {
+	return filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "Start Menu", "Programs", "Boyl"),
+		filepath.Join(os.Getenv("USERPROFILE"), "Desktop")
+}
+
+// createShortcut is run by powershell, the values are passed as environment variables so they don't need to be escaped.
+const createShortcut = `$s = (New-Object -ComObject WScript.Shell).CreateShortcut($env:BOYL_SHORTCUT)
+$s.TargetPath = $env:BOYL_TARGET
+$s.Arguments = $env:BOYL_ARGUMENTS
+$s.Description = $env:BOYL_DESCRIPTION
+if ($env:BOYL_ICON) { $s.IconLocation = $env:BOYL_ICON }
+$s.Save()`
+
+// write creates a shell link through the WScript.Shell COM object.
+func write(directory string, s Shortcut) (string, error) {
+	path := filepath.Join(directory, library.FolderName(s.Name)+".lnk")
+
+	args := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = syscall.EscapeArg(arg)
+	}
+
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", createShortcut)
+	cmd.Env = append(os.Environ(),
+		"BOYL_SHORTCUT="+path,
+		"BOYL_TARGET="+s.Command,
+		"BOYL_ARGUMENTS="+strings.Join(args, " "),
+		"BOYL_DESCRIPTION=Play "+s.Name+" with boyl",
+		"BOYL_ICON="+s.Icon,
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create shortcut: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return path, nil
+}
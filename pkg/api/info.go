@@ -0,0 +1,36 @@
+// Package api holds what the server and the client agree on, so neither has to import the other.
+package api
+
+import "slices"
+
+const (
+	// Protocol is the version of the API. It is raised for changes older clients can't handle,
+	// e. g. a changed schema of the games collection.
+	Protocol = 1
+	// MinProtocol is the oldest protocol still supported on the other end.
+	MinProtocol = 1
+)
+
+// Features of the server the client only uses if the server reports them.
+const (
+	FeaturePlaytime = "playtime"
+	FeatureSaves    = "saves"
+)
+
+// Info describes a server, as reported by its /api/info endpoint.
+type Info struct {
+	Version string `json:"version"`
+	// Protocol is the version of the API the server speaks, MinProtocol the oldest one of clients it still supports.
+	Protocol    int `json:"protocol"`
+	MinProtocol int `json:"minProtocol"`
+	// Formats are the extensions of the archives the games are offered as, e. g. "7z" or "tar.gz".
+	Formats  []string `json:"formats"`
+	Features []string `json:"features"`
+	// Legacy is set for servers from before the endpoint, which are assumed to speak the first protocol without any features.
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// Supports reports whether the server has a feature.
+func (i *Info) Supports(feature string) bool {
+	return slices.Contains(i.Features, feature)
+}
@@ -0,0 +1,295 @@
+package archive_test
+
+import (
+	"boyl/pkg/archive"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// extractedTree walks the extracted directory and describes every entry the same way as expectedTree.
+func extractedTree(t *testing.T, basePath string) map[string]string {
+	t.Helper()
+
+	tree := make(map[string]string)
+	err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == basePath {
+			return nil
+		}
+
+		rel, err := filepath.Rel(basePath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "symlink:" + target
+		case d.IsDir():
+			tree[rel] = "dir"
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "file:" + string(content)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk extracted tree: %v", err)
+	}
+	return tree
+}
+
+// generated caches the fixture archives, since compressing the large fixture is slow.
+var generated = make(map[string][]byte)
+
+func generate(t *testing.T, f fixture, format format) []byte {
+	t.Helper()
+
+	key := f.name + format.ext
+	if data, ok := generated[key]; ok {
+		return data
+	}
+
+	data, err := format.write(f.entries)
+	if err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	generated[key] = data
+	return data
+}
+
+func newExtractor(t *testing.T, f fixture, format format) archive.Extractor {
+	t.Helper()
+
+	if f.hasSymlinks() && !format.symlinks {
+		t.Skip("format does not support symlinks")
+	}
+
+	data := generate(t, f, format)
+
+	extractor, err := archive.NewExtractor(f.name+format.ext, bytes.NewReader(data), int64(len(data)), archive.DefaultLimits)
+	if err != nil {
+		t.Fatalf("failed to create extractor: %v", err)
+	}
+	return extractor
+}
+
+func TestExtract(t *testing.T) {
+	for _, format := range formats {
+		for _, f := range fixtures {
+			t.Run(format.ext+"/"+f.name, func(t *testing.T) {
+				extractor := newExtractor(t, f, format)
+
+				progressSize, err := extractor.GetProgressSize()
+				if err != nil {
+					t.Fatalf("failed to get progress size: %v", err)
+				}
+
+				var mu sync.Mutex
+				var progress []uint64
+				root := t.TempDir()
+				basePath := filepath.Join(root, "base", "game")
+				err = extractor.Extract(context.Background(), basePath, func(u uint64) {
+					mu.Lock()
+					progress = append(progress, u)
+					mu.Unlock()
+				})
+
+				if f.illegal {
+					if !errors.Is(err, archive.ErrIllegalPath) {
+						t.Fatalf("expected %v, got %v", archive.ErrIllegalPath, err)
+					}
+					err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+						if err != nil {
+							return err
+						}
+						if path == basePath {
+							return filepath.SkipDir
+						}
+						if path != root && path != filepath.Dir(basePath) {
+							t.Errorf("%s was written outside of the base path", path)
+						}
+						return nil
+					})
+					if err != nil {
+						t.Fatalf("failed to walk the temporary directory: %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("failed to extract: %v", err)
+				}
+
+				expected := expectedTree(f.entries)
+				actual := extractedTree(t, basePath)
+				for name, want := range expected {
+					if got, ok := actual[name]; !ok {
+						t.Errorf("%s is missing", name)
+					} else if got != want {
+						t.Errorf("%s: expected %.40q, got %.40q", name, want, got)
+					}
+				}
+				for name := range actual {
+					if _, ok := expected[name]; !ok {
+						t.Errorf("%s was not expected", name)
+					}
+				}
+
+				if !slices.IsSorted(progress) {
+					t.Errorf("progress is not monotonic")
+				}
+				var last uint64
+				if len(progress) > 0 {
+					last = progress[len(progress)-1]
+				}
+				if last != progressSize {
+					t.Errorf("expected progress to end at %d, got %d", progressSize, last)
+				}
+			})
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	for _, format := range formats {
+		for _, f := range fixtures {
+			t.Run(format.ext+"/"+f.name, func(t *testing.T) {
+				extractor := newExtractor(t, f, format)
+
+				entries, err := extractor.List()
+				if err != nil {
+					t.Fatalf("failed to list: %v", err)
+				}
+				if len(entries) != len(f.entries) {
+					t.Fatalf("expected %d entries, got %d", len(f.entries), len(entries))
+				}
+
+				for i, entry := range entries {
+					want := f.entries[i]
+					if name := strings.TrimSuffix(entry.Name, "/"); name != want.Name {
+						t.Errorf("expected name %q, got %q", want.Name, name)
+					}
+					if entry.Mode.IsDir() != want.Dir {
+						t.Errorf("%s: expected directory to be %t", want.Name, want.Dir)
+					}
+					if !want.Dir && want.Link == "" && entry.Size != uint64(len(want.Content)) {
+						t.Errorf("%s: expected size %d, got %d", want.Name, len(want.Content), entry.Size)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTest(t *testing.T) {
+	for _, format := range formats {
+		for _, f := range fixtures {
+			t.Run(format.ext+"/"+f.name, func(t *testing.T) {
+				extractor := newExtractor(t, f, format)
+
+				if err := extractor.Test(context.Background()); err != nil {
+					t.Fatalf("expected archive to be intact, got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestTestCorrupt(t *testing.T) {
+	f := fixtures[slices.IndexFunc(fixtures, func(f fixture) bool { return f.name == "large" })]
+
+	for _, format := range formats {
+		if !format.checksums {
+			continue
+		}
+		t.Run(format.ext, func(t *testing.T) {
+			data := bytes.Clone(generate(t, f, format))
+			// the large file makes up most of the archive, so the middle is always file content
+			data[len(data)/2] ^= 0xff
+
+			extractor, err := archive.NewExtractor(f.name+format.ext, bytes.NewReader(data), int64(len(data)), archive.DefaultLimits)
+			if err != nil {
+				t.Fatalf("failed to create extractor: %v", err)
+			}
+			if err := extractor.Test(context.Background()); err == nil {
+				t.Fatalf("expected corrupt archive to fail the test")
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	bomb := fixture{
+		name: "bomb",
+		entries: []fixtureEntry{
+			{Name: "zeros.bin", Content: make([]byte, 4<<20)},
+		},
+	}
+	fixtureByName := func(name string) fixture {
+		return fixtures[slices.IndexFunc(fixtures, func(f fixture) bool { return f.name == name })]
+	}
+
+	tests := []struct {
+		fixture fixture
+		limits  archive.Limits
+		limit   string
+		// compressed is true if the limit only triggers for compressed formats
+		compressed bool
+	}{
+		{fixtureByName("large"), archive.Limits{MaxTotalBytes: 512 << 10}, "total size", false},
+		{fixtureByName("nested"), archive.Limits{MaxFiles: 2}, "file count", false},
+		{fixtureByName("unicode"), archive.Limits{MaxPathLength: 16}, "path length", false},
+		{bomb, archive.DefaultLimits, "compression ratio", true},
+	}
+
+	for _, format := range formats {
+		for _, test := range tests {
+			t.Run(format.ext+"/"+test.limit, func(t *testing.T) {
+				if test.compressed && !format.compressed {
+					t.Skip("fixture is stored without compression")
+				}
+
+				data := generate(t, test.fixture, format)
+				extractor, err := archive.NewExtractor(test.fixture.name+format.ext, bytes.NewReader(data), int64(len(data)), test.limits)
+				if err != nil {
+					t.Fatalf("failed to create extractor: %v", err)
+				}
+
+				// testing an archive decompresses it as well, so it is limited the same way
+				for op, err := range map[string]error{
+					"extract": extractor.Extract(context.Background(), t.TempDir(), nil),
+					"test":    extractor.Test(context.Background()),
+				} {
+					var limitErr *archive.LimitError
+					if !errors.As(err, &limitErr) {
+						t.Fatalf("%s: expected a limit error, got %v", op, err)
+					}
+					if limitErr.Limit != test.limit {
+						t.Errorf("%s: expected the %s limit to be exceeded, got %s", op, test.limit, limitErr.Limit)
+					}
+					if !errors.Is(err, archive.ErrLimitExceeded) {
+						t.Errorf("%s: expected error to wrap ErrLimitExceeded", op)
+					}
+				}
+			})
+		}
+	}
+}
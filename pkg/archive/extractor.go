@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+var ErrIllegalPath = errors.New("illegal path")
+var ErrUnsupportedArchive = errors.New("unsupported archive")
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Entry describes a single file or directory inside an archive.
+type Entry struct {
+	Name string
+	Size uint64
+	Mode fs.FileMode
+	// CRC32 of the uncompressed content. Zero if the format does not store one per entry (tar, rar).
+	CRC32 uint32
+}
+
+// Extractor is an interface for extracting archives.
+type Extractor interface {
+	// Returns the total size for calculating the progress with the progress function in Extract.
+	// If possible, this is the uncompressed size of the archive.
+	// For tar and other sequential archives, this is the size of the archive file.
+	GetProgressSize() (uint64, error)
+	// Extracts the archive to the given basePath. Accepts a context that cancels the extraction. Not resumable.
+	// Fails with a LimitError if the archive exceeds the limits of the extractor.
+	Extract(ctx context.Context, basePath string, progress func(uint64)) error
+	// Returns all entries of the archive without extracting them.
+	List() ([]Entry, error)
+	// Decompresses the whole archive without writing anything to disk and verifies checksums where the format provides them.
+	Test(ctx context.Context) error
+}
+
+// Formats are the extensions of the archives NewExtractor supports.
+var Formats = []string{"zip", "7z", "rar", "tar.gz", "tar.zst", "tar.xz", "tar.lzma", "tar"}
+
+// NewExtractor returns an Extractor for the given archive file.
+func NewExtractor(filename string, r io.ReaderAt, size int64, limits Limits) (Extractor, error) {
+	if strings.HasSuffix(filename, ".zip") {
+		return NewZipExtractor(r, size, limits), nil
+	}
+	if strings.HasSuffix(filename, ".7z") {
+		return NewSevenZipExtractor(r, size, limits), nil
+	}
+	if strings.HasSuffix(filename, ".rar") {
+		return NewRarExtractor(r, size, limits), nil
+	}
+
+	if strings.HasSuffix(filename, ".tar.gz") {
+		return NewTarExtractor(r, size, func(r io.Reader) (io.Reader, error) {
+			return pgzip.NewReader(r)
+		}, limits), nil
+	}
+	if strings.HasSuffix(filename, ".tar.zst") {
+		return NewTarExtractor(r, size, func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		}, limits), nil
+	}
+	if strings.HasSuffix(filename, ".tar.xz") {
+		return NewTarExtractor(r, size, func(r io.Reader) (io.Reader, error) {
+			return xz.NewReader(r)
+		}, limits), nil
+	}
+	if strings.HasSuffix(filename, ".tar.lzma") {
+		return NewTarExtractor(r, size, func(r io.Reader) (io.Reader, error) {
+			return lzma.NewReader(r)
+		}, limits), nil
+	}
+	if strings.HasSuffix(filename, ".tar") {
+		return NewTarExtractor(r, size, nil, limits), nil
+	}
+
+	return nil, ErrUnsupportedArchive
+}
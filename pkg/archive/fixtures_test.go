@@ -0,0 +1,490 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math/rand"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// fixtureEntry is a single entry of a generated test archive.
+type fixtureEntry struct {
+	Name    string
+	Content []byte
+	Dir     bool
+	// Link is the target of a symlink. Formats that store symlinks as content use it as the content.
+	Link string
+}
+
+func (e fixtureEntry) data() []byte {
+	if e.Link != "" {
+		return []byte(e.Link)
+	}
+	return e.Content
+}
+
+func (e fixtureEntry) unixMode() uint32 {
+	switch {
+	case e.Dir:
+		return 0o040755
+	case e.Link != "":
+		return 0o120777
+	default:
+		return 0o100644
+	}
+}
+
+type fixture struct {
+	name    string
+	entries []fixtureEntry
+	// illegal fixtures try to write outside of the base path and have to fail with ErrIllegalPath.
+	illegal bool
+}
+
+func (f fixture) hasSymlinks() bool {
+	for _, e := range f.entries {
+		if e.Link != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+var fixtures = []fixture{
+	{
+		name: "nested",
+		entries: []fixtureEntry{
+			{Name: "game", Dir: true},
+			{Name: "game/bin", Dir: true},
+			{Name: "game/bin/game.exe", Content: []byte("MZ this is not a real executable")},
+			{Name: "game/data/a/b/c.dat", Content: bytes.Repeat([]byte("data"), 1000)},
+			{Name: "game/readme.txt", Content: []byte("have fun")},
+		},
+	},
+	{
+		name: "empty",
+		entries: []fixtureEntry{
+			{Name: "empty.txt"},
+			{Name: "emptydir", Dir: true},
+			{Name: "dir/also-empty"},
+		},
+	},
+	{
+		name: "unicode",
+		entries: []fixtureEntry{
+			{Name: "spiele/größe/ファイル.txt", Content: []byte("grüße")},
+			{Name: "émoji 🎮.txt", Content: []byte("🎮")},
+		},
+	},
+	{
+		name: "large",
+		entries: []fixtureEntry{
+			{Name: "large.bin", Content: randomBytes(1 << 20)},
+			{Name: "small.txt", Content: []byte("small")},
+		},
+	},
+	{
+		name: "symlinks",
+		entries: []fixtureEntry{
+			{Name: "lib/libfoo.so.1", Content: []byte("\x7fELF")},
+			{Name: "lib/libfoo.so", Link: "libfoo.so.1"},
+			{Name: "libs", Link: "lib"},
+		},
+	},
+	{
+		name: "zip-slip",
+		entries: []fixtureEntry{
+			{Name: "ok.txt", Content: []byte("ok")},
+			{Name: "../evil.txt", Content: []byte("evil")},
+		},
+		illegal: true,
+	},
+	{
+		name: "symlink-escape",
+		entries: []fixtureEntry{
+			{Name: "escape", Link: "../../outside"},
+		},
+		illegal: true,
+	},
+	{
+		// each link only goes up within the base path on its own, but the second one is created through the first
+		name: "symlink-chain",
+		entries: []fixtureEntry{
+			{Name: "a/b", Dir: true},
+			{Name: "a/b/x", Link: "../.."},
+			{Name: "a/b/x/y", Link: "../.."},
+			{Name: "a/b/x/y/pwned.txt", Content: []byte("pwned")},
+		},
+		illegal: true,
+	},
+	{
+		// the same chain, but the file comes first, which zip extracts concurrently to the links
+		name: "symlink-chain-reversed",
+		entries: []fixtureEntry{
+			{Name: "a/b/x/y/pwned.txt", Content: []byte("pwned")},
+			{Name: "a/b/x", Link: "../.."},
+			{Name: "a/b/x/y", Link: "../.."},
+		},
+		illegal: true,
+	},
+	{
+		// entries aren't written through links of the archive, even if they stay within the base path
+		name: "symlink-before-files",
+		entries: []fixtureEntry{
+			{Name: "libs", Link: "lib"},
+			{Name: "lib/a.so", Content: []byte("a")},
+			{Name: "libs/b.so", Content: []byte("b")},
+		},
+		illegal: true,
+	},
+}
+
+func writeZip(entries []fixtureEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{
+			Name:   e.Name,
+			Method: zip.Deflate,
+		}
+		switch {
+		case e.Dir:
+			header.Name += "/"
+			header.SetMode(fs.ModeDir | 0755)
+		case e.Link != "":
+			header.SetMode(fs.ModeSymlink | 0777)
+		default:
+			header.SetMode(0644)
+		}
+
+		f, err := w.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(e.data()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTar returns a writer for tar archives compressed with the given function.
+func writeTar(compress func(io.Writer) (io.WriteCloser, error)) func([]fixtureEntry) ([]byte, error) {
+	return func(entries []fixtureEntry) ([]byte, error) {
+		var buf bytes.Buffer
+		var cw io.WriteCloser = nopWriteCloser{&buf}
+		if compress != nil {
+			var err error
+			cw, err = compress(&buf)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		w := tar.NewWriter(cw)
+		for _, e := range entries {
+			header := &tar.Header{
+				Name:     e.Name,
+				Mode:     0644,
+				Size:     int64(len(e.Content)),
+				Typeflag: tar.TypeReg,
+			}
+			switch {
+			case e.Dir:
+				header.Name += "/"
+				header.Mode = 0755
+				header.Typeflag = tar.TypeDir
+			case e.Link != "":
+				header.Mode = 0777
+				header.Typeflag = tar.TypeSymlink
+				header.Linkname = e.Link
+			}
+
+			if err := w.WriteHeader(header); err != nil {
+				return nil, err
+			}
+			if _, err := w.Write(e.Content); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if err := cw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func gzipWriter(w io.Writer) (io.WriteCloser, error) { return pgzip.NewWriter(w), nil }
+func zstdWriter(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+func xzWriter(w io.Writer) (io.WriteCloser, error)   { return xz.NewWriter(w) }
+func lzmaWriter(w io.Writer) (io.WriteCloser, error) { return lzma.NewWriter(w) }
+
+// writeSevenZip writes an uncompressed 7z archive, where every non-empty entry is stored in its own folder using the copy coder.
+func writeSevenZip(entries []fixtureEntry) ([]byte, error) {
+	var packed bytes.Buffer
+	var sizes []uint64
+	var crcs []uint32
+	var emptyStreams, emptyFiles []bool
+	for _, e := range entries {
+		data := e.data()
+		if len(data) == 0 {
+			emptyStreams = append(emptyStreams, true)
+			emptyFiles = append(emptyFiles, !e.Dir)
+			continue
+		}
+		emptyStreams = append(emptyStreams, false)
+		packed.Write(data)
+		sizes = append(sizes, uint64(len(data)))
+		crcs = append(crcs, crc32.ChecksumIEEE(data))
+	}
+
+	var h bytes.Buffer
+	h.WriteByte(0x01) // header
+	if len(sizes) > 0 {
+		h.WriteByte(0x04) // main streams info
+
+		h.WriteByte(0x06) // pack info
+		writeSevenZipNumber(&h, 0)
+		writeSevenZipNumber(&h, uint64(len(sizes)))
+		h.WriteByte(0x09) // size
+		for _, size := range sizes {
+			writeSevenZipNumber(&h, size)
+		}
+		h.WriteByte(0x00)
+
+		h.WriteByte(0x07) // unpack info
+		h.WriteByte(0x0b) // folder
+		writeSevenZipNumber(&h, uint64(len(sizes)))
+		h.WriteByte(0x00) // not external
+		for range sizes {
+			writeSevenZipNumber(&h, 1) // one coder
+			h.WriteByte(0x01)          // simple coder with a codec id of one byte
+			h.WriteByte(0x00)          // copy
+		}
+		h.WriteByte(0x0c) // coders unpack size
+		for _, size := range sizes {
+			writeSevenZipNumber(&h, size)
+		}
+		h.WriteByte(0x00)
+
+		h.WriteByte(0x08) // substreams info
+		h.WriteByte(0x0a) // crc
+		h.WriteByte(0x01) // all defined
+		for _, crc := range crcs {
+			binary.Write(&h, binary.LittleEndian, crc)
+		}
+		h.WriteByte(0x00)
+
+		h.WriteByte(0x00)
+	}
+
+	h.WriteByte(0x05) // files info
+	writeSevenZipNumber(&h, uint64(len(entries)))
+	if len(sizes) < len(entries) {
+		writeSevenZipProperty(&h, 0x0e, sevenZipBitVector(emptyStreams))
+		writeSevenZipProperty(&h, 0x0f, sevenZipBitVector(emptyFiles))
+	}
+
+	names := []byte{0x00} // not external
+	for _, e := range entries {
+		for _, c := range utf16.Encode([]rune(e.Name)) {
+			names = binary.LittleEndian.AppendUint16(names, c)
+		}
+		names = append(names, 0, 0)
+	}
+	writeSevenZipProperty(&h, 0x11, names)
+
+	attributes := []byte{0x01, 0x00} // all defined, not external
+	for _, e := range entries {
+		attribute := 0x8000 | e.unixMode()<<16
+		if e.Dir {
+			attribute |= 0x10
+		}
+		attributes = binary.LittleEndian.AppendUint32(attributes, attribute)
+	}
+	writeSevenZipProperty(&h, 0x15, attributes)
+
+	h.WriteByte(0x00) // end of files info
+	h.WriteByte(0x00) // end of header
+
+	startHeader := binary.LittleEndian.AppendUint64(nil, uint64(packed.Len()))
+	startHeader = binary.LittleEndian.AppendUint64(startHeader, uint64(h.Len()))
+	startHeader = binary.LittleEndian.AppendUint32(startHeader, crc32.ChecksumIEEE(h.Bytes()))
+
+	var buf bytes.Buffer
+	buf.Write([]byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c, 0x00, 0x04})
+	binary.Write(&buf, binary.LittleEndian, crc32.ChecksumIEEE(startHeader))
+	buf.Write(startHeader)
+	buf.Write(packed.Bytes())
+	buf.Write(h.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeSevenZipNumber(w *bytes.Buffer, value uint64) {
+	var first byte
+	mask := byte(0x80)
+	i := 0
+	for ; i < 8; i++ {
+		if value < 1<<(7*(i+1)) {
+			first |= byte(value >> (8 * i))
+			break
+		}
+		first |= mask
+		mask >>= 1
+	}
+	w.WriteByte(first)
+	for ; i > 0; i-- {
+		w.WriteByte(byte(value))
+		value >>= 8
+	}
+}
+
+func writeSevenZipProperty(w *bytes.Buffer, id byte, data []byte) {
+	w.WriteByte(id)
+	writeSevenZipNumber(w, uint64(len(data)))
+	w.Write(data)
+}
+
+func sevenZipBitVector(bits []bool) []byte {
+	vector := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			vector[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return vector
+}
+
+// writeRar writes an uncompressed RAR 5 archive. Symlinks are not supported, since RAR 5 stores them in a redirection record that rardecode does not parse.
+func writeRar(entries []fixtureEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0x52, 0x61, 0x72, 0x21, 0x1a, 0x07, 0x01, 0x00})
+
+	writeRarHeader(&buf, 1, 0, 0, rarVint(nil, 0)) // main archive header
+
+	for _, e := range entries {
+		var body []byte
+		if e.Dir {
+			body = rarVint(body, 0x0001) // directory
+		} else {
+			body = rarVint(body, 0x0004) // crc32 present
+		}
+		body = rarVint(body, uint64(len(e.Content)))
+		body = rarVint(body, uint64(e.unixMode()&0o777))
+		if !e.Dir {
+			body = binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(e.Content))
+		}
+		body = rarVint(body, 0) // stored
+		body = rarVint(body, 1) // unix
+		body = rarVint(body, uint64(len(e.Name)))
+		body = append(body, e.Name...)
+
+		var flags uint64
+		if !e.Dir {
+			flags = 0x0002 // data area present
+		}
+		writeRarHeader(&buf, 2, flags, uint64(len(e.Content)), body)
+		buf.Write(e.Content)
+	}
+
+	writeRarHeader(&buf, 5, 0, 0, rarVint(nil, 0)) // end of archive header
+	return buf.Bytes(), nil
+}
+
+func writeRarHeader(w *bytes.Buffer, headerType, flags, dataSize uint64, body []byte) {
+	header := rarVint(nil, headerType)
+	header = rarVint(header, flags)
+	if flags&0x0002 != 0 {
+		header = rarVint(header, dataSize)
+	}
+	header = append(header, body...)
+
+	sized := rarVint(nil, uint64(len(header)))
+	sized = append(sized, header...)
+
+	binary.Write(w, binary.LittleEndian, crc32.ChecksumIEEE(sized))
+	w.Write(sized)
+}
+
+func rarVint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+type format struct {
+	ext   string
+	write func([]fixtureEntry) ([]byte, error)
+	// symlinks is false for formats whose symlinks can not be generated or extracted.
+	symlinks bool
+	// checksums is true for formats that can detect corrupted content.
+	checksums bool
+	// compressed is false for formats whose fixtures are stored without compression.
+	compressed bool
+}
+
+var formats = []format{
+	{".zip", writeZip, true, true, true},
+	{".7z", writeSevenZip, true, true, false},
+	{".rar", writeRar, false, true, false},
+	{".tar", writeTar(nil), true, false, false},
+	{".tar.gz", writeTar(gzipWriter), true, true, true},
+	{".tar.zst", writeTar(zstdWriter), true, true, true},
+	{".tar.xz", writeTar(xzWriter), true, true, true},
+	{".tar.lzma", writeTar(lzmaWriter), true, false, true},
+}
+
+// expectedTree returns the tree the extracted fixture should produce, including implicit parent directories.
+func expectedTree(entries []fixtureEntry) map[string]string {
+	tree := make(map[string]string)
+	for _, e := range entries {
+		parts := strings.Split(e.Name, "/")
+		for i := 1; i < len(parts); i++ {
+			tree[strings.Join(parts[:i], "/")] = "dir"
+		}
+
+		switch {
+		case e.Dir:
+			tree[e.Name] = "dir"
+		case e.Link != "":
+			tree[e.Name] = "symlink:" + e.Link
+		default:
+			tree[e.Name] = "file:" + string(e.Content)
+		}
+	}
+	return tree
+}
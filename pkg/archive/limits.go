@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var ErrLimitExceeded = errors.New("extraction limit exceeded")
+
+// Limits bounds the resources an extraction may use, to protect against corrupted archives and zip bombs. Zero values disable the respective limit.
+type Limits struct {
+	// Maximum number of bytes written in total.
+	MaxTotalBytes uint64
+	// Maximum number of entries, including directories.
+	MaxFiles uint64
+	// Maximum ratio between the bytes written and the size of the archive.
+	MaxRatio float64
+	// Maximum length of an entry name in bytes.
+	MaxPathLength int
+}
+
+// DefaultLimits are generous enough for any real game, but stop archives that expand without bounds.
+var DefaultLimits = Limits{
+	MaxTotalBytes: 1 << 40,
+	MaxRatio:      100,
+	MaxFiles:      1_000_000,
+	MaxPathLength: 1024,
+}
+
+// LimitError is returned when an extraction exceeds one of its Limits.
+type LimitError struct {
+	// Name of the exceeded limit.
+	Limit string
+	Max   float64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("archive exceeds the %s limit of %v", e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Limiter enforces Limits over a whole extraction. It is safe for concurrent use.
+type Limiter struct {
+	limits      Limits
+	archiveSize int64
+	written     atomic.Uint64
+	files       atomic.Uint64
+}
+
+func NewLimiter(limits Limits, archiveSize int64) *Limiter {
+	return &Limiter{
+		limits:      limits,
+		archiveSize: archiveSize,
+	}
+}
+
+// File registers a new entry with the given name.
+func (l *Limiter) File(name string) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.limits.MaxPathLength > 0 && len(name) > l.limits.MaxPathLength {
+		return &LimitError{Limit: "path length", Max: float64(l.limits.MaxPathLength)}
+	}
+
+	files := l.files.Add(1)
+	if l.limits.MaxFiles > 0 && files > l.limits.MaxFiles {
+		return &LimitError{Limit: "file count", Max: float64(l.limits.MaxFiles)}
+	}
+
+	return nil
+}
+
+// Write registers n bytes that are about to be written.
+func (l *Limiter) Write(n uint64) error {
+	if l == nil {
+		return nil
+	}
+
+	written := l.written.Add(n)
+	if l.limits.MaxTotalBytes > 0 && written > l.limits.MaxTotalBytes {
+		return &LimitError{Limit: "total size", Max: float64(l.limits.MaxTotalBytes)}
+	}
+	if l.limits.MaxRatio > 0 && l.archiveSize > 0 && float64(written)/float64(l.archiveSize) > l.limits.MaxRatio {
+		return &LimitError{Limit: "compression ratio", Max: l.limits.MaxRatio}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nwaples/rardecode"
+)
+
+// ensure RarExtractor implements Extractor
+var _ Extractor = (*RarExtractor)(nil)
+
+type RarExtractor struct {
+	r      io.ReaderAt
+	size   int64
+	limits Limits
+}
+
+func NewRarExtractor(r io.ReaderAt, size int64, limits Limits) *RarExtractor {
+	return &RarExtractor{
+		r:      r,
+		size:   size,
+		limits: limits,
+	}
+}
+
+func (e *RarExtractor) GetProgressSize() (uint64, error) {
+	return uint64(e.size), nil
+}
+
+func (e *RarExtractor) Extract(ctx context.Context, basePath string, progress func(uint64)) error {
+	readCounter := NewReadCounter(io.NewSectionReader(e.r, 0, e.size), progress)
+	rarReader, err := rardecode.NewReader(readCounter, "")
+	if err != nil {
+		return err
+	}
+	limiter := NewLimiter(e.limits, e.size)
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			h, err := rarReader.Next()
+			if err == io.EOF {
+				break loop
+			}
+			if err != nil {
+				return err
+			}
+
+			err = extractRarFile(ctx, h, rarReader, basePath, limiter)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// rar stops reading at its end of archive block, so the rest is read for the progress to reach the progress size
+	return drain(readCounter)
+}
+
+func (e *RarExtractor) List() ([]Entry, error) {
+	rarReader, err := rardecode.NewReader(io.NewSectionReader(e.r, 0, e.size), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for {
+		h, err := rarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{
+			Name: h.Name,
+			Size: uint64(h.UnPackedSize),
+			Mode: h.Mode(),
+		})
+	}
+
+	return entries, nil
+}
+
+// Test relies on rardecode, which verifies the checksum of each file once it is read completely.
+func (e *RarExtractor) Test(ctx context.Context) error {
+	rarReader, err := rardecode.NewReader(io.NewSectionReader(e.r, 0, e.size), "")
+	if err != nil {
+		return err
+	}
+
+	limiter := NewLimiter(e.limits, e.size)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			h, err := rarReader.Next()
+			if err == io.EOF {
+				break loop
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := limiter.File(h.Name); err != nil {
+				return err
+			}
+			if _, err := CopyBufferWithProgress(ctx, io.Discard, rarReader, nil, limiter, nil); err != nil {
+				return fmt.Errorf("%s: %w", h.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func extractRarFile(ctx context.Context, hdr *rardecode.FileHeader, tr io.Reader, basePath string, limiter *Limiter) error {
+	if err := limiter.File(hdr.Name); err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(basePath, hdr.Name)
+	if err := checkPath(basePath, destPath); err != nil {
+		return err
+	}
+
+	if hdr.IsDir {
+		return os.MkdirAll(destPath, 0755)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, hdr.Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = CopyBufferWithProgress(ctx, dst, tr, nil, limiter, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// ensure SevenZipExtractor implements Extractor
+var _ Extractor = (*SevenZipExtractor)(nil)
+
+type SevenZipExtractor struct {
+	r      io.ReaderAt
+	size   int64
+	limits Limits
+}
+
+func NewSevenZipExtractor(r io.ReaderAt, size int64, limits Limits) *SevenZipExtractor {
+	return &SevenZipExtractor{
+		r:      r,
+		size:   size,
+		limits: limits,
+	}
+}
+
+func (e *SevenZipExtractor) GetProgressSize() (uint64, error) {
+	reader, err := sevenzip.NewReader(e.r, e.size)
+	if err != nil {
+		return 0, err
+	}
+
+	var totalSize uint64
+	for _, f := range reader.File {
+		totalSize += f.UncompressedSize
+	}
+
+	return totalSize, nil
+}
+
+func (e *SevenZipExtractor) Extract(ctx context.Context, basePath string, progress func(uint64)) error {
+	zipReader, err := sevenzip.NewReader(e.r, e.size)
+	if err != nil {
+		return err
+	}
+
+	limiter := NewLimiter(e.limits, e.size)
+	var currentSize uint64
+
+	for _, f := range zipReader.File {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			err := extractSevenZipFile(ctx, f, basePath, limiter, func(written uint64) {
+				currentSize += written
+				if progress != nil {
+					progress(currentSize)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *SevenZipExtractor) List() ([]Entry, error) {
+	reader, err := sevenzip.NewReader(e.r, e.size)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range reader.File {
+		entries = append(entries, Entry{
+			Name:  f.Name,
+			Size:  f.UncompressedSize,
+			Mode:  f.Mode(),
+			CRC32: f.CRC32,
+		})
+	}
+
+	return entries, nil
+}
+
+func (e *SevenZipExtractor) Test(ctx context.Context) error {
+	reader, err := sevenzip.NewReader(e.r, e.size)
+	if err != nil {
+		return err
+	}
+
+	limiter := NewLimiter(e.limits, e.size)
+	for _, f := range reader.File {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			err := testSevenZipFile(ctx, f, limiter)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sevenzip does not verify the checksums of the files itself, so the CRC32 is calculated while reading.
+func testSevenZipFile(ctx context.Context, f *sevenzip.File, limiter *Limiter) error {
+	if err := limiter.File(f.Name); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	hash := crc32.NewIEEE()
+	if _, err := CopyBufferWithProgress(ctx, hash, src, nil, limiter, nil); err != nil {
+		return err
+	}
+
+	if f.CRC32 != 0 && hash.Sum32() != f.CRC32 {
+		return ErrChecksum
+	}
+	return nil
+}
+
+func extractSevenZipFile(ctx context.Context, f *sevenzip.File, basePath string, limiter *Limiter, progress func(written uint64)) error {
+	if err := limiter.File(f.Name); err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(basePath, f.Name)
+	if err := checkPath(basePath, destPath); err != nil {
+		return err
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(destPath, 0755)
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if f.Mode()&fs.ModeSymlink != 0 {
+		return extractSymlink(ctx, src, basePath, destPath, limiter, progress)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = CopyBufferWithProgress(ctx, dst, src, nil, limiter, progress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ensure TarExtractor implements Extractor
+var _ Extractor = (*TarExtractor)(nil)
+
+// TarExtractor extracts tar archives. Since tar is used on top of a compression algorithm, a decompress function is accepted that wraps the raw archive reader. The progress is calculated from the compressed bytes read.
+// e. g. ReadCounter -> GzipReader -> TarExtractor
+type TarExtractor struct {
+	r          io.ReaderAt
+	size       int64
+	decompress func(io.Reader) (io.Reader, error)
+	limits     Limits
+}
+
+// NewTarExtractor returns a TarExtractor. decompress may be nil for uncompressed tar archives.
+func NewTarExtractor(r io.ReaderAt, size int64, decompress func(io.Reader) (io.Reader, error), limits Limits) *TarExtractor {
+	return &TarExtractor{
+		r:          r,
+		size:       size,
+		decompress: decompress,
+		limits:     limits,
+	}
+}
+
+// open returns a fresh decompressed stream of the archive, since tar can only be read sequentially.
+func (e *TarExtractor) open(progress func(uint64)) (io.Reader, *ReadCounter, error) {
+	readCounter := NewReadCounter(io.NewSectionReader(e.r, 0, e.size), progress)
+	if e.decompress == nil {
+		return readCounter, readCounter, nil
+	}
+	r, err := e.decompress(readCounter)
+	return r, readCounter, err
+}
+
+func (e *TarExtractor) GetProgressSize() (uint64, error) {
+	return uint64(e.size), nil
+}
+
+func (e *TarExtractor) Extract(ctx context.Context, basePath string, progress func(uint64)) error {
+	r, readCounter, err := e.open(progress)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(r)
+	limiter := NewLimiter(e.limits, e.size)
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			h, err := tarReader.Next()
+			if err == io.EOF {
+				break loop
+			}
+			if err != nil {
+				return err
+			}
+
+			err = extractTarFile(ctx, h, tarReader, basePath, limiter)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// tar stops reading at its end marker, so the rest is read for the progress to reach the progress size
+	if err := drain(r); err != nil {
+		return err
+	}
+	return drain(readCounter)
+}
+
+func (e *TarExtractor) List() ([]Entry, error) {
+	r, _, err := e.open(nil)
+	if err != nil {
+		return nil, err
+	}
+	tarReader := tar.NewReader(r)
+
+	var entries []Entry
+	for {
+		h, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, Entry{
+			Name: h.Name,
+			Size: uint64(h.Size),
+			Mode: h.FileInfo().Mode(),
+		})
+	}
+
+	return entries, nil
+}
+
+func (e *TarExtractor) Test(ctx context.Context) error {
+	r, _, err := e.open(nil)
+	if err != nil {
+		return err
+	}
+	tarReader := tar.NewReader(r)
+
+	limiter := NewLimiter(e.limits, e.size)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			h, err := tarReader.Next()
+			if err == io.EOF {
+				break loop
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := limiter.File(h.Name); err != nil {
+				return err
+			}
+			if _, err := CopyBufferWithProgress(ctx, io.Discard, tarReader, nil, limiter, nil); err != nil {
+				return fmt.Errorf("%s: %w", h.Name, err)
+			}
+		}
+	}
+
+	// the compression formats verify their checksums at the end of the stream, which tar does not read
+	return drain(r)
+}
+
+func extractTarFile(ctx context.Context, hdr *tar.Header, tr *tar.Reader, basePath string, limiter *Limiter) error {
+	if err := limiter.File(hdr.Name); err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(basePath, hdr.Name)
+	if err := checkPath(basePath, destPath); err != nil {
+		return err
+	}
+
+	if hdr.FileInfo().IsDir() {
+		return os.MkdirAll(destPath, 0755)
+	}
+	if hdr.Typeflag == tar.TypeSymlink {
+		return createSymlink(basePath, destPath, hdr.Linkname)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, hdr.FileInfo().Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = CopyBufferWithProgress(ctx, dst, tr, nil, limiter, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
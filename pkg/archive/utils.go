@@ -0,0 +1,190 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func isWithinBase(base, target string) bool {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return false
+	}
+	return !filepath.IsAbs(rel) && !strings.HasPrefix(rel, "..")
+}
+
+// realPath resolves the symlinks in path like filepath.EvalSymlinks, but only a leading part of the path has to exist.
+func realPath(path string) (string, error) {
+	rest := ""
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(path), rest)
+		path = parent
+	}
+}
+
+// checkPath returns ErrIllegalPath if destPath is outside of basePath by its name, or if it goes through a symlink the
+// archive created before. Entries aren't written through links, which could otherwise be chained to get out of the base path.
+func checkPath(basePath, destPath string) error {
+	if !isWithinBase(basePath, destPath) {
+		return ErrIllegalPath
+	}
+	rel, err := filepath.Rel(basePath, destPath)
+	if err != nil {
+		return err
+	}
+
+	path := basePath
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return ErrIllegalPath
+		}
+	}
+	return nil
+}
+
+// createSymlink creates a symlink at destPath, which has to be checked with checkPath. The target has to be relative and
+// stay within basePath when it is resolved from the real directory of the link. A link can't replace a directory,
+// since what was extracted into it would be moved somewhere else.
+func createSymlink(basePath, destPath, target string) error {
+	// once cleaned, all .. of the target come first and go up from the real directory of the link
+	target = filepath.Clean(filepath.FromSlash(target))
+	if filepath.IsAbs(target) {
+		return ErrIllegalPath
+	}
+	realBase, err := realPath(basePath)
+	if err != nil {
+		return err
+	}
+	realParent, err := realPath(filepath.Dir(destPath))
+	if err != nil {
+		return err
+	}
+	if !isWithinBase(realBase, filepath.Join(realParent, target)) {
+		return ErrIllegalPath
+	}
+
+	info, err := os.Lstat(destPath)
+	if err == nil && info.IsDir() {
+		return ErrIllegalPath
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, destPath)
+}
+
+// extractSymlink creates a symlink for formats that store the link target as the content of the entry.
+func extractSymlink(ctx context.Context, src io.Reader, basePath, destPath string, limiter *Limiter, progress func(written uint64)) error {
+	var target strings.Builder
+	_, err := CopyBufferWithProgress(ctx, &target, src, nil, limiter, progress)
+	if err != nil {
+		return err
+	}
+
+	return createSymlink(basePath, destPath, target.String())
+}
+
+// drain reads r until EOF. io.Copy is not used directly, since pgzip panics in WriteTo once the stream was read completely.
+func drain(r io.Reader) error {
+	_, err := io.Copy(io.Discard, struct{ io.Reader }{r})
+	return err
+}
+
+type ReadCounter struct {
+	Total      uint64
+	Progress   func(uint64)
+	underlying io.Reader
+}
+
+func NewReadCounter(r io.Reader, progress func(uint64)) *ReadCounter {
+	return &ReadCounter{
+		underlying: r,
+		Progress:   progress,
+	}
+}
+
+func (r *ReadCounter) Read(p []byte) (n int, err error) {
+	n, err = r.underlying.Read(p)
+	r.Total += uint64(n)
+	if r.Progress != nil {
+		r.Progress(r.Total)
+	}
+	return
+}
+
+// Similar to io.copyBuffer, but with an optional limiter, progress callback and a context for cancellation.
+func CopyBufferWithProgress(ctx context.Context, dst io.Writer, src io.Reader, buf []byte, limiter *Limiter, progress func(written uint64)) (written int64, err error) {
+	if wt, ok := src.(io.WriterTo); ok && progress == nil && limiter == nil {
+		return wt.WriteTo(dst)
+	}
+	if rf, ok := dst.(io.ReaderFrom); ok && progress == nil && limiter == nil {
+		return rf.ReadFrom(src)
+	}
+	if buf == nil {
+		buf = make([]byte, 32*1024)
+	}
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			return written, ctx.Err()
+		default:
+			nr, er := src.Read(buf)
+			if nr > 0 {
+				if el := limiter.Write(uint64(nr)); el != nil {
+					err = el
+					break loop
+				}
+				nw, ew := dst.Write(buf[:nr])
+				if nw < 0 || nr < nw {
+					nw = 0
+					if ew == nil {
+						ew = io.ErrShortWrite
+					}
+				}
+				written += int64(nw)
+				if progress != nil {
+					progress(uint64(nw))
+				}
+				if ew != nil {
+					err = ew
+					break loop
+				}
+				if nr != nw {
+					err = io.ErrShortWrite
+					break loop
+				}
+			}
+			if er != nil {
+				if er != io.EOF {
+					err = er
+				}
+				break loop
+			}
+		}
+	}
+	return written, err
+}
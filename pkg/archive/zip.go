@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ensure ZipExtractor implements Extractor
+var _ Extractor = (*ZipExtractor)(nil)
+
+type ZipExtractor struct {
+	r      io.ReaderAt
+	size   int64
+	limits Limits
+}
+
+func NewZipExtractor(r io.ReaderAt, size int64, limits Limits) *ZipExtractor {
+	return &ZipExtractor{
+		r:      r,
+		size:   size,
+		limits: limits,
+	}
+}
+
+func (e *ZipExtractor) GetProgressSize() (uint64, error) {
+	zipReader, err := zip.NewReader(e.r, e.size)
+	if err != nil {
+		return 0, err
+	}
+
+	var totalSize uint64
+	for _, f := range zipReader.File {
+		totalSize += f.UncompressedSize64
+	}
+
+	return totalSize, nil
+}
+
+func (e *ZipExtractor) Extract(ctx context.Context, basePath string, progress func(uint64)) error {
+	zipReader, err := zip.NewReader(e.r, e.size)
+	if err != nil {
+		return err
+	}
+
+	limiter := NewLimiter(e.limits, e.size)
+	var currentSize uint64
+	var mu sync.Mutex
+	onProgress := func(written uint64) {
+		mu.Lock()
+		currentSize += written
+		if progress != nil {
+			progress(currentSize)
+		}
+		mu.Unlock()
+	}
+
+	// the files are extracted concurrently, so the symlinks are created one by one afterwards,
+	// otherwise a file could be checked before a link on its path exists and be written through it
+	var links []*zip.File
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(runtime.NumCPU())
+	for _, f := range zipReader.File {
+		if f.Mode()&fs.ModeSymlink != 0 {
+			links = append(links, f)
+			continue
+		}
+		eg.Go(func() error {
+			select {
+			case <-egCtx.Done():
+				return egCtx.Err()
+			default:
+				return extractZipFile(egCtx, f, basePath, limiter, onProgress)
+			}
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	for _, f := range links {
+		if err := extractZipFile(ctx, f, basePath, limiter, onProgress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ZipExtractor) List() ([]Entry, error) {
+	zipReader, err := zip.NewReader(e.r, e.size)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range zipReader.File {
+		entries = append(entries, Entry{
+			Name:  f.Name,
+			Size:  f.UncompressedSize64,
+			Mode:  f.Mode(),
+			CRC32: f.CRC32,
+		})
+	}
+
+	return entries, nil
+}
+
+// Test relies on archive/zip, which verifies the CRC32 of each file once it is read completely.
+func (e *ZipExtractor) Test(ctx context.Context) error {
+	zipReader, err := zip.NewReader(e.r, e.size)
+	if err != nil {
+		return err
+	}
+
+	limiter := NewLimiter(e.limits, e.size)
+	for _, f := range zipReader.File {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			err := testZipFile(ctx, f, limiter)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func testZipFile(ctx context.Context, f *zip.File, limiter *Limiter) error {
+	if err := limiter.File(f.Name); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = CopyBufferWithProgress(ctx, io.Discard, src, nil, limiter, nil)
+	if errors.Is(err, zip.ErrChecksum) {
+		return ErrChecksum
+	}
+	return err
+}
+
+func extractZipFile(ctx context.Context, f *zip.File, basePath string, limiter *Limiter, progress func(written uint64)) error {
+	if err := limiter.File(f.Name); err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(basePath, f.Name)
+	if err := checkPath(basePath, destPath); err != nil {
+		return err
+	}
+
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(destPath, 0755)
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if f.Mode()&fs.ModeSymlink != 0 {
+		return extractSymlink(ctx, src, basePath, destPath, limiter, progress)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = CopyBufferWithProgress(ctx, dst, src, nil, limiter, progress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+// Advertiser answers the queries of clients looking for servers, until it is closed.
+type Advertiser struct {
+	conn       *ipv4.PacketConn
+	interfaces []net.Interface
+
+	instance dnsmessage.Name
+	service  dnsmessage.Name
+	host     dnsmessage.Name
+	port     uint16
+	version  string
+}
+
+// Advertise announces a server named name, the name of the machine if empty, whose HTTP server listens on addr.
+// It is announced on the interfaces the HTTP server can be reached on, e. g. only on the loopback interface for 127.0.0.1:8090.
+func Advertise(name, version, addr string) (*Advertiser, error) {
+	if name == "" {
+		name = hostname()
+	}
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q: %w", portString, err)
+	}
+	interfaces, err := interfacesFor(host)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Advertiser{interfaces: interfaces, port: uint16(port), version: version}
+	if a.service, err = dnsmessage.NewName(Service); err != nil {
+		return nil, err
+	}
+	if a.instance, err = dnsmessage.NewName(label(name) + "." + Service); err != nil {
+		return nil, err
+	}
+	if a.host, err = dnsmessage.NewName(label(hostname()) + ".local."); err != nil {
+		return nil, err
+	}
+
+	// other responders on the machine, e. g. Avahi, keep working since the port is shared
+	conn, err := net.ListenMulticastUDP("udp4", &interfaces[0], group)
+	if err != nil {
+		return nil, err
+	}
+	a.conn = ipv4.NewPacketConn(conn)
+	for _, ifi := range interfaces[1:] {
+		// the interface might not have an address yet, the others still work
+		a.conn.JoinGroup(&ifi, group)
+	}
+	// only supported on some systems, answers go out on the default interface otherwise
+	a.conn.SetControlMessage(ipv4.FlagInterface, true)
+
+	go a.serve()
+	return a, nil
+}
+
+// Close stops answering queries.
+func (a *Advertiser) Close() error {
+	return a.conn.Close()
+}
+
+func (a *Advertiser) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, cm, source, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil || header.Response {
+			continue
+		}
+		questions, err := p.AllQuestions()
+		if err != nil || !a.isAsked(questions) {
+			continue
+		}
+
+		var ifIndex int
+		if cm != nil {
+			ifIndex = cm.IfIndex
+		}
+		udpSource, _ := source.(*net.UDPAddr)
+		// queries not sent from the port of mDNS come from simple resolvers that expect a direct answer, see RFC 6762 section 6.7
+		legacy := udpSource != nil && udpSource.Port != group.Port
+		unicast := legacy || questions[0].Class&unicastResponse != 0
+
+		var response []byte
+		if legacy {
+			response, err = a.response(header.ID, questions, ifIndex)
+		} else {
+			response, err = a.response(0, nil, ifIndex)
+		}
+		if err != nil {
+			continue
+		}
+
+		if unicast && udpSource != nil {
+			a.conn.WriteTo(response, nil, udpSource)
+		} else if ifIndex != 0 {
+			a.conn.WriteTo(response, &ipv4.ControlMessage{IfIndex: ifIndex}, group)
+		} else {
+			a.conn.WriteTo(response, nil, group)
+		}
+	}
+}
+
+// isAsked reports whether one of the questions is about the service of the server.
+func (a *Advertiser) isAsked(questions []dnsmessage.Question) bool {
+	for _, question := range questions {
+		switch question.Type {
+		case dnsmessage.TypePTR, dnsmessage.TypeSRV, dnsmessage.TypeTXT, dnsmessage.TypeALL:
+		default:
+			continue
+		}
+		name := question.Name.String()
+		if strings.EqualFold(name, a.service.String()) || strings.EqualFold(name, a.instance.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// response describes the server. The addresses are the ones of the interface the query came in on, if it is known.
+// Legacy queries get their id and questions back.
+func (a *Advertiser) response(id uint16, questions []dnsmessage.Question, ifIndex int) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, question := range questions {
+		if err := b.Question(question); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if err := b.PTRResource(a.header(a.service, false), dnsmessage.PTRResource{PTR: a.instance}); err != nil {
+		return nil, err
+	}
+	if err := b.SRVResource(a.header(a.instance, true), dnsmessage.SRVResource{Target: a.host, Port: a.port}); err != nil {
+		return nil, err
+	}
+	if err := b.TXTResource(a.header(a.instance, true), dnsmessage.TXTResource{TXT: []string{"version=" + a.version}}); err != nil {
+		return nil, err
+	}
+
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	for _, ip := range a.addresses(ifIndex) {
+		var resource dnsmessage.AResource
+		copy(resource.A[:], ip)
+		if err := b.AResource(a.header(a.host, true), resource); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Finish()
+}
+
+func (a *Advertiser) header(name dnsmessage.Name, unique bool) dnsmessage.ResourceHeader {
+	class := dnsmessage.ClassINET
+	if unique {
+		class |= cacheFlush
+	}
+	return dnsmessage.ResourceHeader{Name: name, Class: class, TTL: ttl}
+}
+
+// addresses returns the IPv4 addresses of the interface with the index, of all advertised interfaces if it is 0.
+func (a *Advertiser) addresses(ifIndex int) []net.IP {
+	var ips []net.IP
+	for _, ifi := range a.interfaces {
+		if ifIndex != 0 && ifi.Index != ifIndex {
+			continue
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				ips = append(ips, ipNet.IP.To4())
+			}
+		}
+	}
+	return ips
+}
+
+// interfacesFor returns the interfaces a server listening on host can be reached on.
+func interfacesFor(host string) ([]net.Interface, error) {
+	interfaces, err := multicastInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsUnspecified() {
+		return interfaces, nil
+	}
+
+	for _, ifi := range interfaces {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return []net.Interface{ifi}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w with address %s", ErrNoInterface, host)
+}
+
+// label makes name usable as a single label of a DNS name, which can't contain dots and is at most 63 bytes long.
+func label(name string) string {
+	name = strings.ReplaceAll(name, ".", "-")
+	if len(name) <= 63 {
+		return name
+	}
+	end := 63
+	for !utf8.RuneStart(name[end]) {
+		end--
+	}
+	return name[:end]
+}
+
+// hostname returns the name of the machine without its domain.
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "boyl"
+	}
+	name, _, _ = strings.Cut(name, ".")
+	return name
+}
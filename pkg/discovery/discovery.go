@@ -0,0 +1,209 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+// Service is the DNS-SD service type servers are announced as.
+const Service = "_boyl._tcp.local."
+
+// BrowseTimeout is how long to wait for answers by default, servers on the local network answer well within it.
+const BrowseTimeout = 2 * time.Second
+
+const (
+	// ttl is how long answers may be cached, in seconds.
+	ttl = 120
+	// cacheFlush marks records only the server answers for, see RFC 6762 section 10.2.
+	cacheFlush = 1 << 15
+	// unicastResponse is set in the class of questions asking for a unicast answer.
+	unicastResponse = 1 << 15
+)
+
+var (
+	// ErrNoInterface is returned if there is no network interface to send multicast packets on.
+	ErrNoInterface = errors.New("no multicast network interface")
+
+	group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+)
+
+// Server is a server announced on the network.
+type Server struct {
+	// Name is the instance name the server was announced with.
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// multicastInterfaces returns the interfaces that are up and support multicast, which are used if no interfaces are given.
+// The loopback interface is included for servers on the same machine, Linux delivers multicast on it even without the flag.
+func multicastInterfaces() ([]net.Interface, error) {
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var interfaces []net.Interface
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp != 0 && ifi.Flags&(net.FlagMulticast|net.FlagLoopback) != 0 {
+			interfaces = append(interfaces, ifi)
+		}
+	}
+	if len(interfaces) == 0 {
+		return nil, ErrNoInterface
+	}
+	return interfaces, nil
+}
+
+// Browse asks for servers on the interfaces, all multicast interfaces if none are given,
+// and returns the servers that answered once ctx is done, e. g. after a timeout.
+func Browse(ctx context.Context, interfaces ...net.Interface) ([]Server, error) {
+	if len(interfaces) == 0 {
+		var err error
+		if interfaces, err = multicastInterfaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	// queries from another port than 5353 are answered directly, so nothing has to listen on the port of mDNS
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	p := ipv4.NewPacketConn(conn)
+
+	query, err := newQuery()
+	if err != nil {
+		return nil, err
+	}
+	var sent int
+	var sendErr error
+	for _, ifi := range interfaces {
+		if err := p.SetMulticastInterface(&ifi); err != nil {
+			sendErr = err
+			continue
+		}
+		if _, err := p.WriteTo(query, nil, group); err != nil {
+			sendErr = err
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return nil, sendErr
+	}
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var servers []Server
+	seen := make(map[string]bool)
+	buf := make([]byte, 9000)
+	for {
+		n, source, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return servers, nil
+			}
+			return servers, err
+		}
+		server, ok := parseResponse(buf[:n], source.IP)
+		if !ok || seen[server.Name] {
+			continue
+		}
+		seen[server.Name] = true
+		servers = append(servers, server)
+	}
+}
+
+func newQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(Service)
+	if err != nil {
+		return nil, err
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// parseResponse returns the server described by a response. source is used if the response doesn't include an address.
+func parseResponse(msg []byte, source net.IP) (Server, bool) {
+	var p dnsmessage.Parser
+	header, err := p.Start(msg)
+	if err != nil || !header.Response {
+		return Server{}, false
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return Server{}, false
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return Server{}, false
+	}
+	// the authorities are skipped to get to the addresses in the additional section
+	if err := p.SkipAllAuthorities(); err != nil {
+		return Server{}, false
+	}
+	additionals, _ := p.AllAdditionals()
+
+	var instance, target string
+	var port uint16
+	var version string
+	addresses := make(map[string]net.IP)
+	for _, resource := range append(answers, additionals...) {
+		name := strings.ToLower(resource.Header.Name.String())
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == Service {
+				instance, _ = instanceName(body.PTR.String())
+			}
+		case *dnsmessage.SRVResource:
+			if strings.HasSuffix(name, "."+Service) {
+				target = strings.ToLower(body.Target.String())
+				port = body.Port
+			}
+		case *dnsmessage.TXTResource:
+			for _, entry := range body.TXT {
+				if value, ok := strings.CutPrefix(entry, "version="); ok {
+					version = value
+				}
+			}
+		case *dnsmessage.AResource:
+			addresses[name] = net.IP(body.A[:])
+		}
+	}
+	if instance == "" || port == 0 {
+		return Server{}, false
+	}
+
+	address := source
+	if ip, ok := addresses[target]; ok {
+		address = ip
+	}
+	return Server{
+		Name:    instance,
+		Version: version,
+		URL:     "http://" + net.JoinHostPort(address.String(), strconv.Itoa(int(port))),
+	}, true
+}
+
+// instanceName returns the instance part of the full name of a service instance, e. g. "Home" for "Home._boyl._tcp.local.".
+func instanceName(name string) (string, bool) {
+	suffix := "." + Service
+	if len(name) <= len(suffix) || !strings.EqualFold(name[len(name)-len(suffix):], suffix) {
+		return "", false
+	}
+	return name[:len(name)-len(suffix)], true
+}
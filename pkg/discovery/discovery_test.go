@@ -0,0 +1,58 @@
+package discovery_test
+
+import (
+	"boyl/pkg/discovery"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func loopback(t *testing.T) net.Interface {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ifi := range interfaces {
+		if ifi.Flags&net.FlagLoopback != 0 && ifi.Flags&net.FlagUp != 0 {
+			return ifi
+		}
+	}
+	t.Skip("no loopback interface")
+	return net.Interface{}
+}
+
+func TestBrowseFindsAdvertisedServer(t *testing.T) {
+	lo := loopback(t)
+	advertiser, err := discovery.Advertise("Home NAS v1.2", "1.2.3", "127.0.0.1:8090")
+	if err != nil {
+		t.Skipf("multicast isn't available: %v", err)
+	}
+	defer advertiser.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	servers, err := discovery.Browse(ctx, lo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := discovery.Server{Name: "Home NAS v1-2", Version: "1.2.3", URL: "http://127.0.0.1:8090"}
+	if len(servers) != 1 || servers[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, servers)
+	}
+}
+
+func TestBrowseWithoutServers(t *testing.T) {
+	lo := loopback(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	servers, err := discovery.Browse(ctx, lo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(servers) != 0 {
+		t.Errorf("expected no servers, got %+v", servers)
+	}
+}
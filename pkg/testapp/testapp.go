@@ -0,0 +1,26 @@
+// Package testapp creates PocketBase apps for tests.
+package testapp
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// New returns an app in a temporary directory with all migrations applied. The caller has to import the migrations
+// of the client or the server, so they are registered.
+func New(t *testing.T) core.App {
+	t.Helper()
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("failed to bootstrap app: %v", err)
+	}
+	t.Cleanup(func() {
+		app.ResetBootstrapState()
+	})
+	if err := app.RunAllMigrations(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return app
+}
@@ -0,0 +1,147 @@
+package access
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Roles of users. Users without a role are members, superusers count as admins.
+const (
+	// RoleAdmin can scan the library, edit games and manage the groups.
+	RoleAdmin = "admin"
+	// RoleMember can download the games visible to them and sync playtime and saves.
+	RoleMember = "member"
+	// RoleGuest can only browse the games visible to them.
+	RoleGuest = "guest"
+)
+
+var ErrGameNotFound = errors.New("game not found")
+
+// Role returns the role of an authenticated record.
+func Role(auth *core.Record) string {
+	if auth.IsSuperuser() {
+		return RoleAdmin
+	}
+	if role := auth.GetString("role"); role != "" {
+		return role
+	}
+	return RoleMember
+}
+
+// IsAdmin reports whether the record of a request is allowed to manage the server.
+func IsAdmin(auth *core.Record) bool {
+	return auth != nil && Role(auth) == RoleAdmin
+}
+
+// FindGame returns a game if the user of the request is allowed to see it. Hidden games aren't found,
+// so their existence isn't revealed. The view rule of the games collection decides, the same as for the API of PocketBase.
+func FindGame(e *core.RequestEvent, id string) (*core.Record, error) {
+	game, err := e.App.FindRecordById("games", id)
+	if err != nil {
+		return nil, ErrGameNotFound
+	}
+	info, err := e.RequestInfo()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := e.App.CanAccessRecord(game, info, game.Collection().ViewRule)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return game, nil
+}
+
+// Visible reports whether a user is allowed to see a game outside of a request, e. g. for the sessions a client syncs.
+// The view rule of the games collection decides, like for FindGame.
+func Visible(app core.App, auth, game *core.Record) (bool, error) {
+	return app.CanAccessRecord(game, &core.RequestInfo{Auth: auth}, game.Collection().ViewRule)
+}
+
+// Hidden reports whether a group isn't allowed to see a game. Groups can be limited to genres, then games need one of them,
+// and to a maximum age rating, then games without a rating are hidden as well.
+func Hidden(game, group *core.Record) bool {
+	var genres []string
+	group.UnmarshalJSONField("genres", &genres)
+	if len(genres) > 0 {
+		var gameGenres []string
+		game.UnmarshalJSONField("genres", &gameGenres)
+		if !slices.ContainsFunc(gameGenres, func(genre string) bool { return slices.Contains(genres, genre) }) {
+			return true
+		}
+	}
+
+	if maxAgeRating := group.GetInt("maxAgeRating"); maxAgeRating > 0 {
+		ageRating := game.GetInt("ageRating")
+		if ageRating == 0 || ageRating > maxAgeRating {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind keeps the hiddenFrom field of the games up to date, which the rules of the games collection use.
+// It is set whenever a game is saved, e. g. by a scan, and for all games once a group changes.
+func Bind(app core.App) {
+	app.OnRecordCreate("games").BindFunc(func(e *core.RecordEvent) error {
+		if err := updateGame(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdate("games").BindFunc(func(e *core.RecordEvent) error {
+		if err := updateGame(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	app.OnRecordAfterCreateSuccess("groups").BindFunc(func(e *core.RecordEvent) error {
+		if err := updateGroup(e.App, e.Record); err != nil {
+			e.App.Logger().Error("failed to update the games of a group", "group", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+	app.OnRecordAfterUpdateSuccess("groups").BindFunc(func(e *core.RecordEvent) error {
+		if err := updateGroup(e.App, e.Record); err != nil {
+			e.App.Logger().Error("failed to update the games of a group", "group", e.Record.Id, "error", err)
+		}
+		return e.Next()
+	})
+}
+
+func updateGame(app core.App, game *core.Record) error {
+	groups, err := app.FindAllRecords("groups")
+	if err != nil {
+		return err
+	}
+	var hiddenFrom []string
+	for _, group := range groups {
+		if Hidden(game, group) {
+			hiddenFrom = append(hiddenFrom, group.Id)
+		}
+	}
+	game.Set("hiddenFrom", hiddenFrom)
+	return nil
+}
+
+// updateGroup saves the games whose visibility for the group changed, which updates their hiddenFrom field.
+func updateGroup(app core.App, group *core.Record) error {
+	games, err := app.FindAllRecords("games")
+	if err != nil {
+		return err
+	}
+	for _, game := range games {
+		if Hidden(game, group) == slices.Contains(game.GetStringSlice("hiddenFrom"), group.Id) {
+			continue
+		}
+		if err := app.Save(game); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package access_test
+
+import (
+	"boyl/server/access"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestHidden(t *testing.T) {
+	games := core.NewBaseCollection("games")
+	games.Fields.Add(&core.JSONField{Name: "genres"}, &core.NumberField{Name: "ageRating"})
+	groups := core.NewBaseCollection("groups")
+	groups.Fields.Add(&core.JSONField{Name: "genres"}, &core.NumberField{Name: "maxAgeRating"})
+
+	tests := []struct {
+		name         string
+		genres       string
+		ageRating    int
+		groupGenres  string
+		maxAgeRating int
+		expected     bool
+	}{
+		{"no limits", `["Shooter"]`, 18, `[]`, 0, false},
+		{"allowed genre", `["Puzzle","Adventure"]`, 0, `["Adventure"]`, 0, false},
+		{"other genre", `["Shooter"]`, 0, `["Adventure"]`, 0, true},
+		{"no genres", `[]`, 0, `["Adventure"]`, 0, true},
+		{"rated for the age", `["Puzzle"]`, 7, `[]`, 12, false},
+		{"rated too high", `["Puzzle"]`, 16, `[]`, 12, true},
+		{"not rated", `["Puzzle"]`, 0, `[]`, 12, true},
+		{"both limits", `["Puzzle"]`, 3, `["Puzzle"]`, 12, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := core.NewRecord(games)
+			game.Set("genres", test.genres)
+			game.Set("ageRating", test.ageRating)
+			group := core.NewRecord(groups)
+			group.Set("genres", test.groupGenres)
+			group.Set("maxAgeRating", test.maxAgeRating)
+
+			if hidden := access.Hidden(game, group); hidden != test.expected {
+				t.Errorf("expected hidden to be %v, got %v", test.expected, hidden)
+			}
+		})
+	}
+}
+
+func TestRole(t *testing.T) {
+	users := core.NewAuthCollection("users")
+	users.Fields.Add(&core.SelectField{Name: "role", Values: []string{access.RoleAdmin, access.RoleMember, access.RoleGuest}})
+
+	user := core.NewRecord(users)
+	if role := access.Role(user); role != access.RoleMember {
+		t.Errorf("expected users without a role to be members, got %q", role)
+	}
+	user.Set("role", access.RoleGuest)
+	if access.IsAdmin(user) {
+		t.Error("expected guests not to be admins")
+	}
+	if !access.IsAdmin(core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers))) {
+		t.Error("expected superusers to be admins")
+	}
+}
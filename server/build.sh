@@ -1,3 +1,3 @@
 #!/bin/bash
 
-CGO_ENABLED=0 go build -o build/server main.go
+CGO_ENABLED=0 go build -ldflags="-X main.version=${REF_NAME:-dev}" -o build/server main.go
@@ -0,0 +1,70 @@
+package invites
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var ErrInvalidInvite = errors.New("invalid invite")
+
+// Registration is what a new user sends to create their account.
+type Registration struct {
+	Invite   string `json:"invite"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+// Check returns an error wrapping ErrInvalidInvite if an invite can't be used anymore.
+func Check(invite *core.Record, now time.Time) error {
+	if invite.GetBool("revoked") {
+		return fmt.Errorf("%w: it was revoked", ErrInvalidInvite)
+	}
+	if expires := invite.GetDateTime("expires"); !expires.IsZero() && now.After(expires.Time()) {
+		return fmt.Errorf("%w: it expired", ErrInvalidInvite)
+	}
+	if maxUses := invite.GetInt("maxUses"); maxUses > 0 && invite.GetInt("uses") >= maxUses {
+		return fmt.Errorf("%w: it was used up", ErrInvalidInvite)
+	}
+	return nil
+}
+
+// Register creates a verified user with the role and group of an invite and counts the use of the invite.
+// Both happen in one transaction, so an invite can't be used more often than allowed by concurrent registrations.
+func Register(app core.App, invites, users *core.Collection, registration Registration) (*core.Record, error) {
+	if registration.Invite == "" {
+		return nil, ErrInvalidInvite
+	}
+
+	var user *core.Record
+	err := app.RunInTransaction(func(txApp core.App) error {
+		invite, err := txApp.FindFirstRecordByData(invites, "token", registration.Invite)
+		if err != nil {
+			return ErrInvalidInvite
+		}
+		if err := Check(invite, time.Now()); err != nil {
+			return err
+		}
+
+		user = core.NewRecord(users)
+		user.SetEmail(registration.Email)
+		user.SetPassword(registration.Password)
+		user.SetVerified(true)
+		user.Set("name", registration.Name)
+		user.Set("role", invite.GetString("role"))
+		user.Set("group", invite.GetString("group"))
+		if err := txApp.Save(user); err != nil {
+			return err
+		}
+
+		invite.Set("uses", invite.GetInt("uses")+1)
+		return txApp.Save(invite)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,156 @@
+package invites_test
+
+import (
+	"boyl/pkg/testapp"
+	"boyl/server/invites"
+	"errors"
+	"testing"
+	"time"
+
+	_ "boyl/server/migrations"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestCheck(t *testing.T) {
+	collection := core.NewBaseCollection("invites")
+	collection.Fields.Add(
+		&core.NumberField{Name: "maxUses"},
+		&core.NumberField{Name: "uses"},
+		&core.DateField{Name: "expires"},
+		&core.BoolField{Name: "revoked"},
+	)
+	now := time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		maxUses int
+		uses    int
+		expires time.Time
+		revoked bool
+		valid   bool
+	}{
+		{"unlimited", 0, 12, time.Time{}, false, true},
+		{"uses left", 3, 2, time.Time{}, false, true},
+		{"used up", 3, 3, time.Time{}, false, false},
+		{"not expired", 0, 0, now.Add(time.Hour), false, true},
+		{"expired", 0, 0, now.Add(-time.Hour), false, false},
+		{"revoked", 0, 0, time.Time{}, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invite := core.NewRecord(collection)
+			invite.Set("maxUses", test.maxUses)
+			invite.Set("uses", test.uses)
+			if !test.expires.IsZero() {
+				invite.Set("expires", test.expires)
+			}
+			invite.Set("revoked", test.revoked)
+
+			err := invites.Check(invite, now)
+			if test.valid && err != nil {
+				t.Errorf("expected the invite to be valid, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, invites.ErrInvalidInvite) {
+				t.Errorf("expected ErrInvalidInvite, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	app := testapp.New(t)
+	collections := map[string]*core.Collection{}
+	for _, name := range []string{"invites", "users", "groups"} {
+		collection, err := app.FindCollectionByNameOrId(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collections[name] = collection
+	}
+
+	group := core.NewRecord(collections["groups"])
+	group.Set("name", "Kids")
+	if err := app.Save(group); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		maxUses int
+		uses    int
+		expires time.Time
+		valid   bool
+	}{
+		{"uses left", 2, 1, time.Time{}, true},
+		{"used up", 1, 1, time.Time{}, false},
+		{"expired", 0, 0, time.Now().Add(-time.Hour), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invite := core.NewRecord(collections["invites"])
+			invite.Set("role", "guest")
+			invite.Set("group", group.Id)
+			invite.Set("maxUses", test.maxUses)
+			invite.Set("uses", test.uses)
+			if !test.expires.IsZero() {
+				invite.Set("expires", test.expires)
+			}
+			if err := app.Save(invite); err != nil {
+				t.Fatal(err)
+			}
+
+			email := invite.Id + "@example.com"
+			_, err := invites.Register(app, collections["invites"], collections["users"], invites.Registration{
+				Invite:   invite.GetString("token"),
+				Email:    email,
+				Password: "password123",
+				Name:     "New User",
+			})
+			invite, findErr := app.FindRecordById(collections["invites"], invite.Id)
+			if findErr != nil {
+				t.Fatal(findErr)
+			}
+
+			if !test.valid {
+				if !errors.Is(err, invites.ErrInvalidInvite) {
+					t.Errorf("expected ErrInvalidInvite, got %v", err)
+				}
+				if _, err := app.FindAuthRecordByEmail(collections["users"], email); err == nil {
+					t.Error("expected no user to be created")
+				}
+				if invite.GetInt("uses") != test.uses {
+					t.Errorf("expected the uses to stay at %d, got %d", test.uses, invite.GetInt("uses"))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := app.FindAuthRecordByEmail(collections["users"], email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.GetString("role") != "guest" || user.GetString("group") != group.Id || user.GetString("name") != "New User" {
+				t.Errorf("expected the role and group of the invite, got %q and %q", user.GetString("role"), user.GetString("group"))
+			}
+			if !user.Verified() || !user.ValidatePassword("password123") {
+				t.Error("expected a verified user with the password")
+			}
+			if invite.GetInt("uses") != test.uses+1 {
+				t.Errorf("expected the use to be counted, got %d uses", invite.GetInt("uses"))
+			}
+		})
+	}
+
+	if _, err := invites.Register(app, collections["invites"], collections["users"], invites.Registration{
+		Invite:   "unknown",
+		Email:    "unknown@example.com",
+		Password: "password123",
+	}); !errors.Is(err, invites.ErrInvalidInvite) {
+		t.Errorf("expected ErrInvalidInvite for an unknown invite, got %v", err)
+	}
+}
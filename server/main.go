@@ -1,26 +1,39 @@
 package main
 
 import (
+	"boyl/pkg/api"
 	"context"
 	"errors"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/plugins/migratecmd"
 
+	"boyl/pkg/discovery"
+	"boyl/server/access"
+	"boyl/server/invites"
 	_ "boyl/server/migrations"
+	"boyl/server/playtime"
+	"boyl/server/saves"
 	"boyl/server/scan"
 	"boyl/server/scan/metadata"
 	"boyl/server/scan/metadata/gog"
 	"boyl/server/scan/metadata/igdb"
 	"boyl/server/scan/metadata/steam"
+	"boyl/server/transfers"
 )
 
+// version is set when building a release, see build.sh.
+var version = "dev"
+
 func loadEnv(name string) string {
 	value := os.Getenv(name)
 	if value == "" {
@@ -39,6 +52,8 @@ func main() {
 		Automigrate: isGoRun,
 	})
 
+	access.Bind(app)
+
 	app.OnServe().BindFunc(func(se *core.ServeEvent) error {
 		se.InstallerFunc = func(app core.App, systemSuperuser *core.Record, baseURL string) error {
 			email := os.Getenv("ADMIN_EMAIL")
@@ -64,6 +79,36 @@ func main() {
 		if err != nil {
 			return err
 		}
+		sessionsCollection, err := app.FindCollectionByNameOrId("sessions")
+		if err != nil {
+			return err
+		}
+		savesCollection, err := app.FindCollectionByNameOrId("saves")
+		if err != nil {
+			return err
+		}
+		invitesCollection, err := app.FindCollectionByNameOrId("invites")
+		if err != nil {
+			return err
+		}
+		usersCollection, err := app.FindCollectionByNameOrId("users")
+		if err != nil {
+			return err
+		}
+		usageCollection, err := app.FindCollectionByNameOrId("usage")
+		if err != nil {
+			return err
+		}
+		transfersCollection, err := app.FindCollectionByNameOrId("transfers")
+		if err != nil {
+			return err
+		}
+
+		limits, err := transfers.LimitsFromEnv()
+		if err != nil {
+			return err
+		}
+		manager := transfers.NewManager(app, usageCollection, transfersCollection, limits)
 
 		igdbProvider := igdb.NewProvider(igdbClientID, igdbClientSecret)
 		gogProvider := gog.NewProvider()
@@ -80,16 +125,49 @@ func main() {
 			statusCollection,
 		)
 
+		// public, clients check they can talk to the server before logging in
+		se.Router.GET("/api/info", func(e *core.RequestEvent) error {
+			return e.JSON(200, api.Info{
+				Version:     version,
+				Protocol:    api.Protocol,
+				MinProtocol: api.MinProtocol,
+				Formats:     scan.Formats(),
+				Features:    []string{api.FeaturePlaytime, api.FeatureSaves},
+			})
+		})
+
+		// public, accounts can only be created with an invite, which decides the role of the user
+		se.Router.POST("/api/register", func(e *core.RequestEvent) error {
+			var body invites.Registration
+			if err := e.BindBody(&body); err != nil {
+				return e.BadRequestError("invalid body", err)
+			}
+
+			user, err := invites.Register(app, invitesCollection, usersCollection, body)
+			if errors.Is(err, invites.ErrInvalidInvite) {
+				return e.BadRequestError(err.Error(), nil)
+			}
+			if err != nil {
+				return e.BadRequestError("failed to register", err)
+			}
+
+			return apis.RecordAuthResponse(e, user, core.MFAMethodPassword, nil)
+		})
+
 		se.Router.GET("/api/download", func(e *core.RequestEvent) error {
 			if e.Auth == nil || (!e.Auth.IsSuperuser() && e.Auth.GetBool("verified") != true) {
 				return e.UnauthorizedError("unauthorized", nil)
 			}
 
+			if access.Role(e.Auth) == access.RoleGuest {
+				return e.ForbiddenError("guests can't download games", nil)
+			}
+
 			id := e.Request.URL.Query().Get("id")
 			if id == "" {
 				return e.BadRequestError("id is required", nil)
 			}
-			game, err := app.FindRecordById("games", id)
+			game, err := access.FindGame(e, id)
 			if err != nil {
 				return e.BadRequestError("game not found", nil)
 			}
@@ -111,23 +189,54 @@ func main() {
 				return e.InternalServerError("error while getting file info", err)
 			}
 
-			log.Printf("kb size is %d", info.Size()/1024)
+			transfer, err := manager.Start(e.Auth, game, info.Size(), e.Request, e.RealIP())
+			var limitErr *transfers.LimitError
+			if errors.As(err, &limitErr) {
+				e.Response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+				return e.TooManyRequestsError(limitErr.Message, nil)
+			}
+			if err != nil {
+				return e.InternalServerError("error while checking the download limits", err)
+			}
+			defer func() {
+				if err := transfer.Finish(); err != nil {
+					log.Printf("error while logging the download: %v", err)
+				}
+			}()
 
-			http.ServeContent(e.Response, e.Request, info.Name(), info.ModTime(), file)
+			http.ServeContent(transfer.Writer(e.Response), e.Request, info.Name(), info.ModTime(), file)
 			return nil
 		})
 
+		se.Router.GET("/api/transfers/active", func(e *core.RequestEvent) error {
+			if e.Auth == nil || (!e.Auth.IsSuperuser() && e.Auth.GetBool("verified") != true) {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+			if !access.IsAdmin(e.Auth) {
+				return e.ForbiddenError("only admins can see the running downloads", nil)
+			}
+
+			return e.JSON(200, manager.Active())
+		})
+
 		se.Router.GET("/api/scan", func(e *core.RequestEvent) error {
 			if e.Auth == nil || (!e.Auth.IsSuperuser() && e.Auth.GetBool("verified") != true) {
 				return e.UnauthorizedError("unauthorized", nil)
 			}
+			if !access.IsAdmin(e.Auth) {
+				return e.ForbiddenError("only admins can scan the library", nil)
+			}
 
 			if scanner.IsScanning() {
 				return e.JSON(200, "Scanning in progress")
 			}
 
+			options := scan.UpdateOptions{
+				TestArchives: e.Request.URL.Query().Get("test") == "true",
+			}
+
 			go func() {
-				err := scanner.Update(context.Background())
+				err := scanner.Update(context.Background(), options)
 				if err != nil {
 					app.Logger().Error("error while scanning", "error", err)
 				}
@@ -135,6 +244,160 @@ func main() {
 
 			return nil
 		})
+
+		se.Router.POST("/api/playtime", func(e *core.RequestEvent) error {
+			if e.Auth == nil || e.Auth.Collection().Name != "users" || e.Auth.GetBool("verified") != true {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+			if access.Role(e.Auth) == access.RoleGuest {
+				return e.ForbiddenError("guests can't sync playtime and saves", nil)
+			}
+
+			var body struct {
+				Sessions []playtime.Session `json:"sessions"`
+			}
+			if err := e.BindBody(&body); err != nil {
+				return e.BadRequestError("invalid body", err)
+			}
+
+			result, err := playtime.Sync(app, sessionsCollection, e.Auth, body.Sessions)
+			if err != nil {
+				return e.InternalServerError("error while syncing playtime", err)
+			}
+
+			return e.JSON(200, result)
+		})
+
+		se.Router.GET("/api/saves/latest", func(e *core.RequestEvent) error {
+			if e.Auth == nil || e.Auth.Collection().Name != "users" || e.Auth.GetBool("verified") != true {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+			if access.Role(e.Auth) == access.RoleGuest {
+				return e.ForbiddenError("guests can't sync playtime and saves", nil)
+			}
+
+			game := e.Request.URL.Query().Get("game")
+			if game == "" {
+				return e.BadRequestError("game is required", nil)
+			}
+			if _, err := access.FindGame(e, game); err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+
+			latest, err := saves.Latest(app, savesCollection, e.Auth.Id, game)
+			if err != nil {
+				return e.InternalServerError("error while getting saves", err)
+			}
+			if latest == nil {
+				return e.NotFoundError("no saves uploaded", nil)
+			}
+
+			return e.JSON(200, latest)
+		})
+
+		se.Router.POST("/api/saves", func(e *core.RequestEvent) error {
+			if e.Auth == nil || e.Auth.Collection().Name != "users" || e.Auth.GetBool("verified") != true {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+			if access.Role(e.Auth) == access.RoleGuest {
+				return e.ForbiddenError("guests can't sync playtime and saves", nil)
+			}
+
+			q := e.Request.URL.Query()
+			upload := saves.Upload{
+				Game:    q.Get("game"),
+				Hash:    q.Get("hash"),
+				Machine: q.Get("machine"),
+				Force:   q.Get("force") == "true",
+			}
+			if upload.Game == "" || upload.Hash == "" {
+				return e.BadRequestError("game and hash are required", nil)
+			}
+			if _, err := access.FindGame(e, upload.Game); err != nil {
+				return e.NotFoundError("game not found", nil)
+			}
+			if base := q.Get("base"); base != "" {
+				version, err := strconv.Atoi(base)
+				if err != nil {
+					return e.BadRequestError("invalid base", err)
+				}
+				upload.Base = version
+			}
+
+			files, err := e.FindUploadedFiles("archive")
+			if err != nil || len(files) != 1 {
+				return e.BadRequestError("archive is required", err)
+			}
+
+			record, err := saves.Store(app, savesCollection, e.Auth.Id, upload, files[0])
+			if errors.Is(err, saves.ErrConflict) {
+				return e.Error(http.StatusConflict, err.Error(), nil)
+			}
+			if err != nil {
+				return e.InternalServerError("error while storing saves", err)
+			}
+
+			return e.JSON(200, record)
+		}).Bind(apis.BodyLimit(saves.MaxArchiveSize + apis.DefaultMaxBodySize))
+
+		se.Router.GET("/api/saves/download", func(e *core.RequestEvent) error {
+			if e.Auth == nil || e.Auth.Collection().Name != "users" || e.Auth.GetBool("verified") != true {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+			if access.Role(e.Auth) == access.RoleGuest {
+				return e.ForbiddenError("guests can't sync playtime and saves", nil)
+			}
+
+			id := e.Request.URL.Query().Get("id")
+			if id == "" {
+				return e.BadRequestError("id is required", nil)
+			}
+			record, err := app.FindRecordById(savesCollection, id)
+			if err != nil || record.GetString("user") != e.Auth.Id {
+				return e.NotFoundError("saves not found", nil)
+			}
+
+			fsys, err := app.NewFilesystem()
+			if err != nil {
+				return e.InternalServerError("error while opening storage", err)
+			}
+			defer fsys.Close()
+
+			name := record.GetString("archive")
+			return fsys.Serve(e.Response, e.Request, record.BaseFilesPath()+"/"+name, name)
+		})
+
+		se.Router.GET("/api/stats/playtime", func(e *core.RequestEvent) error {
+			if !access.IsAdmin(e.Auth) {
+				return e.UnauthorizedError("unauthorized", nil)
+			}
+
+			limit, err := strconv.Atoi(e.Request.URL.Query().Get("limit"))
+			if err != nil || limit <= 0 {
+				limit = 20
+			}
+
+			stats, err := playtime.GetStats(app, limit)
+			if err != nil {
+				return e.InternalServerError("error while getting stats", err)
+			}
+
+			return e.JSON(200, stats)
+		})
+
+		// clients on the local network find the server without typing its URL, unless DISCOVERY is false
+		if os.Getenv("DISCOVERY") != "false" {
+			advertiser, err := discovery.Advertise(os.Getenv("SERVER_NAME"), version, se.Server.Addr)
+			if err != nil {
+				app.Logger().Warn("failed to advertise the server on the network", "error", err)
+			} else {
+				app.OnTerminate().BindFunc(func(te *core.TerminateEvent) error {
+					advertiser.Close()
+					return te.Next()
+				})
+			}
+		}
+
 		return se.Next()
 	})
 
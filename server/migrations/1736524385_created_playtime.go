@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 0,
+					"min": 0,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "_clone_uMI3",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "user",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_879072730",
+					"hidden": false,
+					"id": "_clone_MljB",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "game",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "json3257917790",
+					"maxSize": 1,
+					"name": "total",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "json"
+				},
+				{
+					"hidden": false,
+					"id": "number2590022931",
+					"max": null,
+					"min": null,
+					"name": "sessions",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "json2198999173",
+					"maxSize": 1,
+					"name": "lastPlayed",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "json"
+				}
+			],
+			"id": "pbc_1826423375",
+			"indexes": [],
+			"listRule": "user = @request.auth.id",
+			"name": "playtime",
+			"system": false,
+			"type": "view",
+			"updateRule": null,
+			"viewQuery": "SELECT\n  (ROW_NUMBER() OVER()) AS id,\n  user,\n  game,\n  SUM(duration) AS total,\n  COUNT(*) AS sessions,\n  MAX(start) AS lastPlayed\nFROM sessions\nGROUP BY user, game",
+			"viewRule": "user = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_1826423375")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
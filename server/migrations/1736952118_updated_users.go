@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"createRule": "@request.body.role:isset = false && @request.body.group:isset = false && @request.body.maxAgeRating:isset = false",
+			"updateRule": "id = @request.auth.id && @request.body.role:isset = false && @request.body.group:isset = false && @request.body.maxAgeRating:isset = false"
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(8, []byte(`{
+			"hidden": false,
+			"id": "select1466534506",
+			"maxSelect": 1,
+			"name": "role",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "select",
+			"values": [
+				"admin",
+				"member",
+				"guest"
+			]
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(9, []byte(`{
+			"cascadeDelete": false,
+			"collectionId": "pbc_3279196605",
+			"hidden": false,
+			"id": "relation1841317061",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "group",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(10, []byte(`{
+			"hidden": false,
+			"id": "number490895374",
+			"max": null,
+			"min": 0,
+			"name": "maxAgeRating",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("_pb_users_auth_")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"createRule": "",
+			"updateRule": "id = @request.auth.id"
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("select1466534506")
+
+		// remove field
+		collection.Fields.RemoveById("relation1841317061")
+
+		// remove field
+		collection.Fields.RemoveById("number490895374")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_879072730")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"listRule": "@request.auth.id != \"\" && (@request.auth.role = \"admin\" || ((@request.auth.group = \"\" || hiddenFrom.id != @request.auth.group) && (@request.auth.maxAgeRating = 0 || (ageRating > 0 && ageRating <= @request.auth.maxAgeRating))))",
+			"updateRule": "@request.auth.role = \"admin\" && @request.body.path:isset = false && @request.body.hiddenFrom:isset = false",
+			"viewRule": "@request.auth.id != \"\" && (@request.auth.role = \"admin\" || ((@request.auth.group = \"\" || hiddenFrom.id != @request.auth.group) && (@request.auth.maxAgeRating = 0 || (ageRating > 0 && ageRating <= @request.auth.maxAgeRating))))"
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(10, []byte(`{
+			"hidden": false,
+			"id": "number3226913054",
+			"max": null,
+			"min": 0,
+			"name": "ageRating",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`)); err != nil {
+			return err
+		}
+
+		// add field
+		if err := collection.Fields.AddMarshaledJSONAt(17, []byte(`{
+			"cascadeDelete": false,
+			"collectionId": "pbc_3279196605",
+			"hidden": false,
+			"id": "relation3161807661",
+			"maxSelect": 999,
+			"minSelect": 0,
+			"name": "hiddenFrom",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_879072730")
+		if err != nil {
+			return err
+		}
+
+		// update collection data
+		if err := json.Unmarshal([]byte(`{
+			"listRule": "@request.auth.id != \"\"",
+			"updateRule": null,
+			"viewRule": "@request.auth.id != \"\""
+		}`), &collection); err != nil {
+			return err
+		}
+
+		// remove field
+		collection.Fields.RemoveById("number3226913054")
+
+		// remove field
+		collection.Fields.RemoveById("relation3161807661")
+
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": "@request.auth.role = \"admin\" && @request.body.token:isset = false && @request.body.uses:isset = false",
+			"deleteRule": "@request.auth.role = \"admin\"",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "[a-zA-Z0-9]{32}",
+					"hidden": false,
+					"id": "text1597481275",
+					"max": 0,
+					"min": 0,
+					"name": "token",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text3485334036",
+					"max": 0,
+					"min": 0,
+					"name": "note",
+					"pattern": "",
+					"presentable": true,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "select1466534506",
+					"maxSelect": 1,
+					"name": "role",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "select",
+					"values": [
+						"admin",
+						"member",
+						"guest"
+					]
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "pbc_3279196605",
+					"hidden": false,
+					"id": "relation1841317061",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "group",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "number115675991",
+					"max": null,
+					"min": 0,
+					"name": "maxUses",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "number4204062431",
+					"max": null,
+					"min": 0,
+					"name": "uses",
+					"onlyInt": true,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"hidden": false,
+					"id": "date2593941644",
+					"max": "",
+					"min": "",
+					"name": "expires",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "bool3181538509",
+					"name": "revoked",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "bool"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_3545406027",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_invites_token` + "`" + ` ON ` + "`" + `invites` + "`" + ` (` + "`" + `token` + "`" + `)"
+			],
+			"listRule": "@request.auth.role = \"admin\"",
+			"name": "invites",
+			"system": false,
+			"type": "base",
+			"updateRule": "@request.auth.role = \"admin\" && @request.body.token:isset = false && @request.body.uses:isset = false",
+			"viewRule": "@request.auth.role = \"admin\""
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_3545406027")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
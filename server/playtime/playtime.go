@@ -0,0 +1,147 @@
+package playtime
+
+import (
+	"boyl/server/access"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	// MaxDuration is the longest a single session may last, anything longer is a broken clock or a bogus client.
+	MaxDuration = 72 * time.Hour
+	// clockSkew is how far the clock of a client may be ahead of the server.
+	clockSkew = 5 * time.Minute
+)
+
+// Session is a play session as reported by a client. ID is the id of the session on the client, which makes syncing idempotent.
+type Session struct {
+	ID       string    `json:"id"`
+	Game     string    `json:"game"`
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration"`
+}
+
+type SyncResult struct {
+	Synced  int `json:"synced"`
+	Skipped int `json:"skipped"`
+}
+
+// Valid reports whether a session could have happened, so a single bogus session can't dominate the stats.
+// It can't have lasted longer than the time since it started, nor longer than MaxDuration.
+func (s Session) Valid(now time.Time) bool {
+	if s.ID == "" || s.Start.IsZero() || s.Duration < 0 {
+		return false
+	}
+	// compared in seconds, since huge durations overflow time.Duration
+	return s.Duration <= MaxDuration.Seconds() && s.Duration <= (now.Sub(s.Start)+clockSkew).Seconds()
+}
+
+// Sync stores the sessions of a user. Sessions that were synced before are updated, since a running session keeps growing.
+// Sessions of games that don't exist on the server or are hidden from the user, and invalid sessions are skipped.
+func Sync(app core.App, collection *core.Collection, user *core.Record, sessions []Session) (*SyncResult, error) {
+	result := &SyncResult{}
+	now := time.Now()
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		for _, session := range sessions {
+			if !session.Valid(now) {
+				result.Skipped++
+				continue
+			}
+			game, err := txApp.FindRecordById("games", session.Game)
+			if err != nil {
+				result.Skipped++
+				continue
+			}
+			visible, err := access.Visible(txApp, user, game)
+			if err != nil {
+				return err
+			}
+			if !visible {
+				result.Skipped++
+				continue
+			}
+
+			record, err := txApp.FindFirstRecordByFilter(
+				collection,
+				"user = {:user} && session = {:session}",
+				dbx.Params{"user": user.Id, "session": session.ID},
+			)
+			if err != nil {
+				record = core.NewRecord(collection)
+				record.Set("user", user.Id)
+				record.Set("session", session.ID)
+			}
+			record.Set("game", session.Game)
+			record.Set("start", session.Start)
+			record.Set("duration", session.Duration)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+			result.Synced++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type GameStats struct {
+	Game       string         `db:"game" json:"game"`
+	Name       string         `db:"name" json:"name"`
+	Total      float64        `db:"total" json:"total"`
+	Sessions   int            `db:"sessions" json:"sessions"`
+	Players    int            `db:"players" json:"players"`
+	LastPlayed types.DateTime `db:"lastPlayed" json:"lastPlayed"`
+}
+
+type Stats struct {
+	MostPlayed  []GameStats `json:"mostPlayed"`
+	NeverPlayed []GameStats `json:"neverPlayed"`
+}
+
+// GetStats returns the most played games across all users and the available games nobody has played yet.
+func GetStats(app core.App, limit int) (*Stats, error) {
+	stats := &Stats{
+		MostPlayed:  []GameStats{},
+		NeverPlayed: []GameStats{},
+	}
+
+	err := app.DB().
+		Select(
+			"sessions.game AS game",
+			"games.name AS name",
+			"SUM(sessions.duration) AS total",
+			"COUNT(*) AS sessions",
+			"COUNT(DISTINCT sessions.user) AS players",
+			"MAX(sessions.start) AS lastPlayed",
+		).
+		From("sessions").
+		InnerJoin("games", dbx.NewExp("games.id = sessions.game")).
+		GroupBy("sessions.game").
+		OrderBy("total DESC").
+		Limit(int64(limit)).
+		All(&stats.MostPlayed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.DB().
+		Select("games.id AS game", "games.name AS name").
+		From("games").
+		Where(dbx.Not(dbx.HashExp{"status": "deleted"})).
+		AndWhere(dbx.NewExp("games.id NOT IN (SELECT game FROM sessions)")).
+		OrderBy("games.name ASC").
+		All(&stats.NeverPlayed)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
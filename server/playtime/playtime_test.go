@@ -0,0 +1,187 @@
+package playtime_test
+
+import (
+	"boyl/pkg/testapp"
+	"boyl/server/playtime"
+	"math"
+	"testing"
+	"time"
+
+	_ "boyl/server/migrations"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestValid(t *testing.T) {
+	now := time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		session  playtime.Session
+		expected bool
+	}{
+		{"finished", playtime.Session{ID: "s1", Start: now.Add(-2 * time.Hour), Duration: 3600}, true},
+		{"running", playtime.Session{ID: "s1", Start: now.Add(-time.Hour), Duration: 3600}, true},
+		{"clock ahead", playtime.Session{ID: "s1", Start: now.Add(time.Minute), Duration: 60}, true},
+		{"no id", playtime.Session{Start: now.Add(-time.Hour), Duration: 60}, false},
+		{"no start", playtime.Session{ID: "s1", Duration: 60}, false},
+		{"negative", playtime.Session{ID: "s1", Start: now.Add(-time.Hour), Duration: -1}, false},
+		{"longer than since the start", playtime.Session{ID: "s1", Start: now.Add(-time.Hour), Duration: 2 * 3600}, false},
+		{"longer than the maximum", playtime.Session{ID: "s1", Start: now.AddDate(0, -1, 0), Duration: 7 * 24 * 3600}, false},
+		{"overflowing", playtime.Session{ID: "s1", Start: now.AddDate(-1, 0, 0), Duration: math.MaxFloat64}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if valid := test.session.Valid(now); valid != test.expected {
+				t.Errorf("expected valid to be %v, got %v", test.expected, valid)
+			}
+		})
+	}
+}
+
+// setup creates two users and four games, the third of which is deleted.
+func setup(t *testing.T) (core.App, *core.Collection, []*core.Record, []string) {
+	t.Helper()
+	app := testapp.New(t)
+
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var userRecords []*core.Record
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		user := core.NewRecord(users)
+		user.SetEmail(email)
+		user.SetPassword("password123")
+		if err := app.Save(user); err != nil {
+			t.Fatal(err)
+		}
+		userRecords = append(userRecords, user)
+	}
+
+	games, err := app.FindCollectionByNameOrId("games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gameIDs []string
+	for _, name := range []string{"Alpha", "Beta", "Gamma", "Delta"} {
+		game := core.NewRecord(games)
+		game.Set("name", name)
+		if name == "Gamma" {
+			game.Set("status", "deleted")
+		}
+		if err := app.Save(game); err != nil {
+			t.Fatal(err)
+		}
+		gameIDs = append(gameIDs, game.Id)
+	}
+
+	sessions, err := app.FindCollectionByNameOrId("sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, sessions, userRecords, gameIDs
+}
+
+func TestSync(t *testing.T) {
+	app, sessions, users, games := setup(t)
+	start := time.Now().Add(-time.Hour).UTC()
+
+	running := []playtime.Session{
+		{ID: "s1", Game: games[0], Start: start, Duration: 60},
+		{ID: "s2", Game: "missing", Start: start, Duration: 60},
+		{ID: "s3", Game: games[0], Start: start, Duration: 10 * 3600},
+	}
+	result, err := playtime.Sync(app, sessions, users[0], running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Synced != 1 || result.Skipped != 2 {
+		t.Errorf("expected 1 synced and 2 skipped sessions, got %+v", result)
+	}
+
+	// the session grew in the meantime, syncing it again updates it instead of adding another one
+	running[0].Duration = 120
+	if _, err := playtime.Sync(app, sessions, users[0], running[:1]); err != nil {
+		t.Fatal(err)
+	}
+	records, err := app.FindAllRecords(sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].GetFloat("duration") != 120 {
+		t.Fatalf("expected a single session of 120s, got %d sessions", len(records))
+	}
+
+	// the same id from another user is another session
+	if _, err := playtime.Sync(app, sessions, users[1], running[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := app.CountRecords(sessions); err != nil || count != 2 {
+		t.Errorf("expected 2 sessions, got %d, %v", count, err)
+	}
+
+	// games without an age rating are hidden from users limited to one
+	users[1].Set("maxAgeRating", 12)
+	if err := app.Save(users[1]); err != nil {
+		t.Fatal(err)
+	}
+	result, err = playtime.Sync(app, sessions, users[1], []playtime.Session{{ID: "s4", Game: games[1], Start: start, Duration: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Synced != 0 || result.Skipped != 1 {
+		t.Errorf("expected the session of a hidden game to be skipped, got %+v", result)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	app, sessions, users, games := setup(t)
+	start := time.Now().Add(-24 * time.Hour).UTC()
+
+	synced := map[*core.Record][]playtime.Session{
+		users[0]: {
+			{ID: "s1", Game: games[0], Start: start, Duration: 600},
+			{ID: "s2", Game: games[1], Start: start.Add(time.Hour), Duration: 3600},
+		},
+		users[1]: {
+			{ID: "s1", Game: games[0], Start: start.Add(2 * time.Hour), Duration: 1800},
+		},
+	}
+	for user, s := range synced {
+		if _, err := playtime.Sync(app, sessions, user, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := playtime.GetStats(app, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.MostPlayed) != 2 {
+		t.Fatalf("expected 2 played games, got %+v", stats.MostPlayed)
+	}
+	beta, alpha := stats.MostPlayed[0], stats.MostPlayed[1]
+	if beta.Name != "Beta" || beta.Total != 3600 || beta.Sessions != 1 || beta.Players != 1 {
+		t.Errorf("unexpected stats for Beta: %+v", beta)
+	}
+	if alpha.Name != "Alpha" || alpha.Total != 2400 || alpha.Sessions != 2 || alpha.Players != 2 {
+		t.Errorf("unexpected stats for Alpha: %+v", alpha)
+	}
+	if !alpha.LastPlayed.Time().Equal(start.Add(2 * time.Hour).Truncate(time.Millisecond)) {
+		t.Errorf("expected Alpha to be last played at %s, got %s", start.Add(2*time.Hour), alpha.LastPlayed)
+	}
+	// deleted games can't be played anymore
+	if len(stats.NeverPlayed) != 1 || stats.NeverPlayed[0].Game != games[3] {
+		t.Errorf("expected only Delta to be never played, got %+v", stats.NeverPlayed)
+	}
+
+	limited, err := playtime.GetStats(app, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited.MostPlayed) != 1 || limited.MostPlayed[0].Name != "Beta" {
+		t.Errorf("expected only the most played game, got %+v", limited.MostPlayed)
+	}
+}
@@ -0,0 +1,115 @@
+package saves
+
+import (
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// Keep is the number of versions kept per user and game, older versions are deleted on upload.
+const Keep = 10
+
+// MaxArchiveSize matches the maxSize of the archive field.
+const MaxArchiveSize = 1 << 30
+
+// ErrConflict is returned when the saves were uploaded by another machine since the version an upload is based on.
+var ErrConflict = errors.New("saves were changed on another machine")
+
+// Upload is a new version of the saves of a game.
+type Upload struct {
+	Game string
+	// Base is the version the client synced last, 0 if it never synced.
+	Base    int
+	Hash    string
+	Machine string
+	// Force stores the upload even if it conflicts with the latest version.
+	Force bool
+}
+
+// Latest returns the newest version of the saves of a user for a game, or nil if there is none.
+func Latest(app core.App, collection *core.Collection, user, game string) (*core.Record, error) {
+	records, err := app.FindRecordsByFilter(
+		collection,
+		"user = {:user} && game = {:game}",
+		"-version",
+		1,
+		0,
+		dbx.Params{"user": user, "game": game},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
+
+// Store saves the archive as the next version of the saves of a user. An archive with the same hash as the latest
+// version isn't stored again, in which case the latest version is returned.
+func Store(app core.App, collection *core.Collection, user string, upload Upload, archive *filesystem.File) (*core.Record, error) {
+	if _, err := app.FindRecordById("games", upload.Game); err != nil {
+		return nil, err
+	}
+
+	var record *core.Record
+	err := app.RunInTransaction(func(txApp core.App) error {
+		latest, err := Latest(txApp, collection, user, upload.Game)
+		if err != nil {
+			return err
+		}
+
+		version := 1
+		if latest != nil {
+			if latest.GetString("hash") == upload.Hash {
+				record = latest
+				return nil
+			}
+			if latest.GetInt("version") != upload.Base && !upload.Force {
+				return ErrConflict
+			}
+			version = latest.GetInt("version") + 1
+		}
+
+		record = core.NewRecord(collection)
+		record.Set("user", user)
+		record.Set("game", upload.Game)
+		record.Set("version", version)
+		record.Set("hash", upload.Hash)
+		record.Set("machine", upload.Machine)
+		record.Set("size", archive.Size)
+		record.Set("archive", archive)
+		if err := txApp.Save(record); err != nil {
+			return err
+		}
+
+		return prune(txApp, collection, user, upload.Game)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func prune(app core.App, collection *core.Collection, user, game string) error {
+	records, err := app.FindRecordsByFilter(
+		collection,
+		"user = {:user} && game = {:game}",
+		"-version",
+		0,
+		Keep,
+		dbx.Params{"user": user, "game": game},
+	)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := app.Delete(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package saves_test
+
+import (
+	"boyl/pkg/testapp"
+	"boyl/server/saves"
+	"errors"
+	"strconv"
+	"testing"
+
+	_ "boyl/server/migrations"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+func setup(t *testing.T) (core.App, *core.Collection, string, string) {
+	t.Helper()
+	app := testapp.New(t)
+
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := core.NewRecord(users)
+	user.SetEmail("a@example.com")
+	user.SetPassword("password123")
+	if err := app.Save(user); err != nil {
+		t.Fatal(err)
+	}
+
+	games, err := app.FindCollectionByNameOrId("games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := core.NewRecord(games)
+	game.Set("name", "Alpha")
+	if err := app.Save(game); err != nil {
+		t.Fatal(err)
+	}
+
+	collection, err := app.FindCollectionByNameOrId("saves")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, collection, user.Id, game.Id
+}
+
+func archive(t *testing.T, content string) *filesystem.File {
+	t.Helper()
+	file, err := filesystem.NewFileFromBytes([]byte(content), "saves.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestStore(t *testing.T) {
+	app, collection, user, game := setup(t)
+
+	first, err := saves.Store(app, collection, user, saves.Upload{Game: game, Hash: "h1", Machine: "desktop"}, archive(t, "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.GetInt("version") != 1 {
+		t.Errorf("expected version 1, got %d", first.GetInt("version"))
+	}
+
+	// the same saves again aren't stored, whatever they are based on
+	same, err := saves.Store(app, collection, user, saves.Upload{Game: game, Hash: "h1", Machine: "laptop"}, archive(t, "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.Id != first.Id {
+		t.Errorf("expected the latest version for an identical hash, got version %d", same.GetInt("version"))
+	}
+
+	second, err := saves.Store(app, collection, user, saves.Upload{Game: game, Base: 1, Hash: "h2", Machine: "desktop"}, archive(t, "2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.GetInt("version") != 2 {
+		t.Errorf("expected version 2, got %d", second.GetInt("version"))
+	}
+
+	// the laptop synced version 1 last and didn't see version 2
+	_, err = saves.Store(app, collection, user, saves.Upload{Game: game, Base: 1, Hash: "h3", Machine: "laptop"}, archive(t, "3"))
+	if !errors.Is(err, saves.ErrConflict) {
+		t.Fatalf("expected a conflict for a stale base, got %v", err)
+	}
+	latest, err := saves.Latest(app, collection, user, game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Id != second.Id {
+		t.Errorf("expected a conflict not to be stored, got version %d", latest.GetInt("version"))
+	}
+
+	forced, err := saves.Store(app, collection, user, saves.Upload{Game: game, Base: 1, Hash: "h3", Machine: "laptop", Force: true}, archive(t, "3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forced.GetInt("version") != 3 {
+		t.Errorf("expected a forced upload to be version 3, got %d", forced.GetInt("version"))
+	}
+}
+
+func TestStoreUnknownGame(t *testing.T) {
+	app, collection, user, _ := setup(t)
+
+	if _, err := saves.Store(app, collection, user, saves.Upload{Game: "missing", Hash: "h1"}, archive(t, "1")); err == nil {
+		t.Error("expected an error for an unknown game")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	app, collection, user, game := setup(t)
+
+	for version := 1; version <= saves.Keep+3; version++ {
+		upload := saves.Upload{Game: game, Base: version - 1, Hash: "h" + strconv.Itoa(version)}
+		if _, err := saves.Store(app, collection, user, upload, archive(t, strconv.Itoa(version))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := app.FindRecordsByFilter(
+		collection,
+		"user = {:user} && game = {:game}",
+		"version",
+		0,
+		0,
+		dbx.Params{"user": user, "game": game},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != saves.Keep {
+		t.Fatalf("expected %d versions to be kept, got %d", saves.Keep, len(records))
+	}
+	if oldest := records[0].GetInt("version"); oldest != 4 {
+		t.Errorf("expected the oldest versions to be deleted, the oldest kept is %d", oldest)
+	}
+}
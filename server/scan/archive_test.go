@@ -0,0 +1,69 @@
+package scan_test
+
+import (
+	"archive/zip"
+	"boyl/server/scan"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "Valid (2020).zip")
+	file, err := os.Create(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(file)
+	entry, err := w.Create("game.exe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Write([]byte("game"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	broken := filepath.Join(dir, "Broken (2020).zip")
+	if err := os.WriteFile(broken, []byte("not a zip archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unsupported := filepath.Join(dir, "Readme.txt")
+	if err := os.WriteFile(unsupported, []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	folder := filepath.Join(dir, "Folder.zip")
+	if err := os.Mkdir(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		corrupt  bool
+		untested bool
+	}{
+		{"valid", valid, false, false},
+		{"corrupt", broken, true, false},
+		{"missing", filepath.Join(dir, "Missing (2020).zip"), false, true},
+		{"not an archive", unsupported, false, true},
+		{"directory", folder, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := scan.TestArchive(context.Background(), test.path)
+			if untested := errors.Is(err, scan.ErrUntested); untested != test.untested {
+				t.Fatalf("expected untested to be %v, got %v", test.untested, err)
+			}
+			if corrupt := err != nil && !test.untested; corrupt != test.corrupt {
+				t.Errorf("expected corrupt to be %v, got %v", test.corrupt, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package scan
+
+var (
+	TestArchive = testArchive
+	ErrUntested = errUntested
+)
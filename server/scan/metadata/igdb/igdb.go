@@ -23,6 +23,19 @@ func NewProvider(clientID, clientSecret string) *Provider {
 	return &Provider{client: NewClientCredentialsClient(context.Background(), clientID, clientSecret)}
 }
 
+// minimumAges maps the age ratings of IGDB to the minimum age, by category and rating.
+var minimumAges = map[int]map[int]int{
+	// ESRB, rating pending is left out
+	1: {7: 3, 8: 6, 9: 10, 10: 13, 11: 17, 12: 18},
+	// PEGI
+	2: {1: 3, 2: 7, 3: 12, 4: 16, 5: 18},
+}
+
+// minimumAge returns the minimum age of an age rating, zero for unknown ratings.
+func minimumAge(category, rating int) int {
+	return minimumAges[category][rating]
+}
+
 func hqImage(url string) string {
 	new := url
 	if strings.HasPrefix(url, "//") {
@@ -40,6 +53,10 @@ type searchResult struct {
 	Genres           []struct {
 		Name string `json:"name"`
 	} `json:"genres"`
+	AgeRatings []struct {
+		Category int `json:"category"`
+		Rating   int `json:"rating"`
+	} `json:"age_ratings"`
 	Cover struct {
 		URL string `json:"url"`
 	} `json:"cover"`
@@ -60,6 +77,8 @@ func (p *Provider) Find(name string, year int) (*metadata.Game, error) {
 		"first_release_date",
 		"total_rating",
 		"genres.name",
+		"age_ratings.category",
+		"age_ratings.rating",
 		"cover.url",
 		"artworks.url",
 		"screenshots.url",
@@ -101,6 +120,9 @@ func (p *Provider) Find(name string, year int) (*metadata.Game, error) {
 	game.ReleaseDate = time.Unix(int64(result.FirstReleaseDate), 0)
 	game.Rating = result.TotalRating
 	game.Cover = hqImage(result.Cover.URL)
+	for _, rating := range result.AgeRatings {
+		game.AgeRating = max(game.AgeRating, minimumAge(rating.Category, rating.Rating))
+	}
 
 	game.Genres = make([]string, len(result.Genres))
 	for i, genre := range result.Genres {
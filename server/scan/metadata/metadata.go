@@ -10,6 +10,8 @@ type Game struct {
 	Summary     string
 	ReleaseDate time.Time
 	Rating      float64
+	// AgeRating is the minimum age the game is rated for, zero if unknown.
+	AgeRating   int
 	Genres      []string
 	Cover       string
 	Artworks    []string
@@ -1,10 +1,12 @@
 package scan
 
 import (
+	"boyl/pkg/archive"
 	"boyl/server/scan/metadata"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -24,6 +26,11 @@ var extensions = []string{
 	"rar",
 }
 
+// Formats returns the extensions of the archives that are offered as games.
+func Formats() []string {
+	return slices.Clone(extensions)
+}
+
 func isArchive(path string) bool {
 	for _, ext := range extensions {
 		if filepath.Ext(path) == "."+ext {
@@ -52,6 +59,12 @@ type Missing struct {
 	FilenameMetadata *FilenameMetadata
 }
 
+// UpdateOptions configures optional, more expensive steps of Update.
+type UpdateOptions struct {
+	// Decompresses every archive and marks games whose archive fails the integrity test as corrupt.
+	TestArchives bool
+}
+
 type Result struct {
 	Invalid      []string
 	Missing      []Missing
@@ -157,7 +170,7 @@ func (s *Scanner) scan(skip []string, progress chan<- bool) (*Result, error) {
 	return &result, nil
 }
 
-func (s *Scanner) Update(ctx context.Context) error {
+func (s *Scanner) Update(ctx context.Context, options UpdateOptions) error {
 	if s.scanning {
 		return errors.New("scanning in progress")
 	}
@@ -249,6 +262,10 @@ func (s *Scanner) Update(ctx context.Context) error {
 		record.Set("version", match.FilenameMetadata.Version)
 		record.Set("released", match.Game.ReleaseDate)
 		record.Set("rating", match.Game.Rating)
+		// a rating entered by hand is kept for games the provider has none for
+		if match.Game.AgeRating > 0 {
+			record.Set("ageRating", match.Game.AgeRating)
+		}
 
 		marshaledGenres, err := json.Marshal(match.Game.Genres)
 		if err != nil {
@@ -331,5 +348,86 @@ func (s *Scanner) Update(ctx context.Context) error {
 		s.app.Save(status)
 	}
 
+	if options.TestArchives {
+		return s.testArchives(ctx, status)
+	}
+
 	return nil
 }
+
+// testArchives tests the archives of all available games and updates their corrupt flag. Files that are missing or
+// aren't archives can't be tested, they keep their flag and are counted in the status instead.
+func (s *Scanner) testArchives(ctx context.Context, status *core.Record) error {
+	games, err := s.app.FindAllRecords("games")
+	if err != nil {
+		return err
+	}
+
+	status.Set("text", "Testing archives")
+	status.Set("current", 0)
+	status.Set("total", len(games))
+	s.app.Save(status)
+
+	untested := 0
+	for i, game := range games {
+		if game.GetString("status") != "deleted" {
+			path := game.GetString("path")
+			err := testArchive(ctx, path)
+			switch {
+			case errors.Is(err, context.Canceled):
+				return err
+			case errors.Is(err, errUntested):
+				s.app.Logger().Warn("archive can't be tested", "path", path, "error", err)
+				untested++
+			default:
+				if err != nil {
+					s.app.Logger().Error("archive is corrupt", "path", path, "error", err)
+				}
+				game.Set("corrupt", err != nil)
+				if err := s.app.Save(game); err != nil {
+					return err
+				}
+			}
+		}
+
+		status.Set("current", i+1)
+		s.app.Save(status)
+	}
+
+	if untested > 0 {
+		status.Set("text", fmt.Sprintf("Tested archives, %d couldn't be tested since they are missing or not archives", untested))
+		s.app.Save(status)
+	}
+	return nil
+}
+
+// errUntested is returned for files that can't be tested, which says nothing about the integrity of the archive.
+var errUntested = errors.New("archive can't be tested")
+
+// testArchive tests the archive at path. Files that can't be opened and formats that aren't supported are errUntested.
+
+func testArchive(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUntested, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUntested, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%w: %s is a directory", errUntested, path)
+	}
+
+	extractor, err := archive.NewExtractor(filepath.Base(path), file, info.Size(), archive.DefaultLimits)
+	if errors.Is(err, archive.ErrUnsupportedArchive) {
+		return fmt.Errorf("%w: %w", errUntested, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return extractor.Test(ctx)
+}
@@ -0,0 +1,438 @@
+package transfers
+
+import (
+	"boyl/server/access"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"golang.org/x/time/rate"
+)
+
+// dayLayout is the format of the day field of the usage collection.
+const dayLayout = "2006-01-02"
+
+// retryBusy is when to try again after hitting the limit of concurrent downloads, which depends on other downloads finishing.
+const retryBusy = 30 * time.Second
+
+// errQuota stops a download once it used up the quota reserved for it.
+var errQuota = errors.New("download quota used up")
+
+// Limits of the downloads of games, zero means unlimited.
+type Limits struct {
+	// PerUser is how many downloads a user can run at once.
+	PerUser int
+	// Rate is the bandwidth in bytes per second shared by all downloads.
+	Rate float64
+	// Daily and Monthly are the bytes a user can download per day and per calendar month, in the time zone of the server.
+	Daily   int64
+	Monthly int64
+}
+
+// LimitsFromEnv reads the limits from MAX_DOWNLOADS_PER_USER, MAX_DOWNLOAD_RATE in megabytes per second,
+// and DOWNLOAD_QUOTA_DAILY and DOWNLOAD_QUOTA_MONTHLY in gigabytes. Unset variables mean unlimited.
+func LimitsFromEnv() (Limits, error) {
+	var limits Limits
+	var err error
+	if limits.PerUser, err = envInt("MAX_DOWNLOADS_PER_USER"); err != nil {
+		return limits, err
+	}
+	megabytes, err := envFloat("MAX_DOWNLOAD_RATE")
+	if err != nil {
+		return limits, err
+	}
+	limits.Rate = megabytes * 1e6
+	daily, err := envFloat("DOWNLOAD_QUOTA_DAILY")
+	if err != nil {
+		return limits, err
+	}
+	limits.Daily = int64(daily * 1e9)
+	monthly, err := envFloat("DOWNLOAD_QUOTA_MONTHLY")
+	if err != nil {
+		return limits, err
+	}
+	limits.Monthly = int64(monthly * 1e9)
+	return limits, nil
+}
+
+func envInt(name string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+func envFloat(name string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
+
+// LimitError is returned if a user can't start a download right now.
+type LimitError struct {
+	Message string
+	// RetryAfter is when the download is allowed again.
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return e.Message
+}
+
+// Manager applies the limits to the downloads of games, tracks how much each user downloaded in the usage collection
+// and logs every download in the transfers collection.
+type Manager struct {
+	app       core.App
+	usage     *core.Collection
+	transfers *core.Collection
+	limits    Limits
+	limiter   *rate.Limiter
+
+	mu     sync.Mutex
+	active map[*Transfer]struct{}
+}
+
+func NewManager(app core.App, usage, transfers *core.Collection, limits Limits) *Manager {
+	m := &Manager{
+		app:       app,
+		usage:     usage,
+		transfers: transfers,
+		limits:    limits,
+		mu:        sync.Mutex{},
+		active:    make(map[*Transfer]struct{}),
+	}
+	if limits.Rate > 0 {
+		// the burst has to fit a single write of http.ServeContent
+		m.limiter = rate.NewLimiter(rate.Limit(limits.Rate), max(int(limits.Rate), 64<<10))
+	}
+	return m
+}
+
+// Transfer is a running download, which has to be finished once the response is written.
+type Transfer struct {
+	manager *Manager
+	user    *core.Record
+	game    *core.Record
+	request *http.Request
+	ip      string
+	size    int64
+	started time.Time
+	// limited is false for admins, whose downloads only share the bandwidth
+	limited bool
+	// quota is how many bytes the transfer may write, reserved from the quota of the user when it started. Zero means unlimited.
+	quota int64
+
+	mu      sync.Mutex
+	written int64
+	failed  bool
+	// the speed is measured over windows of about a second, so it shows stalled clients
+	window      int64
+	windowStart time.Time
+	speed       float64
+}
+
+// Status is a snapshot of a running download.
+type Status struct {
+	User     string    `json:"user"`
+	Email    string    `json:"email"`
+	Game     string    `json:"game"`
+	GameName string    `json:"gameName"`
+	IP       string    `json:"ip"`
+	Started  time.Time `json:"started"`
+	Bytes    int64     `json:"bytes"`
+	Size     int64     `json:"size"`
+	// Speed is in bytes per second.
+	Speed float64 `json:"speed"`
+}
+
+// Start checks a user is allowed to download a game of the given size now and returns the transfer, or a LimitError if not.
+// What is left of the quota of the user is reserved for the transfer, up to its size, and the transfer stops once it is used up.
+// Admins aren't limited, except by the shared bandwidth.
+func (m *Manager) Start(user, game *core.Record, size int64, r *http.Request, ip string) (*Transfer, error) {
+	now := time.Now()
+	t := &Transfer{
+		manager:     m,
+		user:        user,
+		game:        game,
+		request:     r,
+		ip:          ip,
+		size:        size,
+		started:     now,
+		limited:     !access.IsAdmin(user),
+		windowStart: now,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.limited {
+		if m.limits.PerUser > 0 && m.running(user.Id) >= m.limits.PerUser {
+			return nil, &LimitError{
+				Message:    fmt.Sprintf("at most %d downloads can run at once", m.limits.PerUser),
+				RetryAfter: retryBusy,
+			}
+		}
+		remaining, err := m.remaining(user.Id, now)
+		if err != nil {
+			return nil, err
+		}
+		if remaining > 0 {
+			t.quota = min(size, remaining)
+		}
+	}
+	m.active[t] = struct{}{}
+	return t, nil
+}
+
+// running returns how many limited downloads of a user are running, the caller has to hold mu.
+func (m *Manager) running(user string) int {
+	n := 0
+	for t := range m.active {
+		if t.limited && t.user.Id == user {
+			n++
+		}
+	}
+	return n
+}
+
+// Active returns the running downloads, oldest first.
+func (m *Manager) Active() []Status {
+	m.mu.Lock()
+	transfers := make([]*Transfer, 0, len(m.active))
+	for t := range m.active {
+		transfers = append(transfers, t)
+	}
+	m.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]Status, 0, len(transfers))
+	for _, t := range transfers {
+		statuses = append(statuses, t.status(now))
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return a.Started.Compare(b.Started)
+	})
+	return statuses
+}
+
+func (t *Transfer) status(now time.Time) Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	speed := t.speed
+	if elapsed := now.Sub(t.windowStart); elapsed >= time.Second {
+		// the window is overdue since nothing was written for a while, e. g. at the start or for a stalled client
+		speed = float64(t.window) / elapsed.Seconds()
+	}
+	return Status{
+		User:     t.user.Id,
+		Email:    t.user.Email(),
+		Game:     t.game.Id,
+		GameName: t.game.GetString("name"),
+		IP:       t.ip,
+		Started:  t.started,
+		Bytes:    t.written,
+		Size:     t.size,
+		Speed:    speed,
+	}
+}
+
+// allowed returns how many of n bytes the transfer may still write.
+func (t *Transfer) allowed(n int) int {
+	if t.quota <= 0 {
+		return n
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return int(min(int64(n), max(t.quota-t.written, 0)))
+}
+
+// add counts bytes that were written to the client.
+func (t *Transfer) add(n int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.written += int64(n)
+	t.window += int64(n)
+	if elapsed := now.Sub(t.windowStart); elapsed >= time.Second {
+		t.speed = float64(t.window) / elapsed.Seconds()
+		t.window = 0
+		t.windowStart = now
+	}
+}
+
+// fail marks the transfer as aborted, e. g. because the client went away.
+func (t *Transfer) fail() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failed = true
+}
+
+// remaining returns how many bytes a user may still download, zero if there is no quota, or a LimitError if it is used up.
+// The quota reserved by the running downloads of the user counts as used, so the caller has to hold mu.
+func (m *Manager) remaining(user string, now time.Time) (int64, error) {
+	if m.limits.Daily <= 0 && m.limits.Monthly <= 0 {
+		return 0, nil
+	}
+	daily, monthly, err := m.Usage(user, now)
+	if err != nil {
+		return 0, err
+	}
+	for t := range m.active {
+		if t.limited && t.user.Id == user {
+			daily += t.quota
+			monthly += t.quota
+		}
+	}
+
+	var remaining int64
+	if m.limits.Monthly > 0 {
+		if monthly >= m.limits.Monthly {
+			next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+			return 0, &LimitError{Message: "the monthly download quota is used up", RetryAfter: next.Sub(now)}
+		}
+		remaining = m.limits.Monthly - monthly
+	}
+	if m.limits.Daily > 0 {
+		if daily >= m.limits.Daily {
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			return 0, &LimitError{Message: "the daily download quota is used up", RetryAfter: next.Sub(now)}
+		}
+		if remaining == 0 || m.limits.Daily-daily < remaining {
+			remaining = m.limits.Daily - daily
+		}
+	}
+	return remaining, nil
+}
+
+// Usage returns the bytes a user downloaded on the day and in the month of now.
+func (m *Manager) Usage(user string, now time.Time) (daily, monthly int64, err error) {
+	today := now.Format(dayLayout)
+	records, err := m.app.FindAllRecords(m.usage, dbx.HashExp{"user": user}, dbx.Like("day", today[:len("2006-01-")]).Match(false, true))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, record := range records {
+		bytes := int64(record.GetInt("bytes"))
+		monthly += bytes
+		if record.GetString("day") == today {
+			daily += bytes
+		}
+	}
+	return daily, monthly, nil
+}
+
+// Writer returns the writer the response has to be written to, which counts the bytes and shapes the bandwidth.
+func (t *Transfer) Writer(w http.ResponseWriter) http.ResponseWriter {
+	return &writer{ResponseWriter: w, transfer: t}
+}
+
+// Finish ends the transfer, adds the bytes that were sent to the usage of the user and logs the download.
+// The download counts as aborted if the client went away before the response was written.
+func (t *Transfer) Finish() error {
+	m := t.manager
+	// the reserved quota is only released once the bytes were added to the usage, so they are counted all along
+	defer func() {
+		m.mu.Lock()
+		delete(m.active, t)
+		m.mu.Unlock()
+	}()
+
+	// clients check the size with a HEAD request before downloading, which isn't worth logging
+	if t.request.Method == http.MethodHead {
+		return nil
+	}
+
+	t.mu.Lock()
+	written := t.written
+	status := "completed"
+	if t.failed || t.request.Context().Err() != nil {
+		status = "aborted"
+	}
+	t.mu.Unlock()
+
+	return m.app.RunInTransaction(func(txApp core.App) error {
+		// superusers aren't users and have no usage
+		isUser := t.user.Collection().Name == "users"
+		if written > 0 && isUser {
+			day := time.Now().Format(dayLayout)
+			record, err := txApp.FindFirstRecordByFilter(m.usage, "user = {:user} && day = {:day}", dbx.Params{"user": t.user.Id, "day": day})
+			if err != nil {
+				record = core.NewRecord(m.usage)
+				record.Set("user", t.user.Id)
+				record.Set("day", day)
+			}
+			record.Set("bytes", int64(record.GetInt("bytes"))+written)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+		}
+
+		record := core.NewRecord(m.transfers)
+		if isUser {
+			record.Set("user", t.user.Id)
+		}
+		record.Set("email", t.user.Email())
+		record.Set("game", t.game.Id)
+		record.Set("gameName", t.game.GetString("name"))
+		record.Set("ip", t.ip)
+		record.Set("bytes", written)
+		record.Set("size", t.size)
+		record.Set("started", t.started)
+		record.Set("duration", time.Since(t.started).Seconds())
+		record.Set("status", status)
+		return txApp.Save(record)
+	})
+}
+
+type writer struct {
+	http.ResponseWriter
+	transfer *Transfer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	limiter := w.transfer.manager.limiter
+	var n int
+	for len(p) > 0 {
+		chunk := p[:w.transfer.allowed(len(p))]
+		if len(chunk) == 0 {
+			w.transfer.fail()
+			return n, errQuota
+		}
+		if limiter != nil {
+			if len(chunk) > limiter.Burst() {
+				chunk = chunk[:limiter.Burst()]
+			}
+			if err := limiter.WaitN(w.transfer.request.Context(), len(chunk)); err != nil {
+				w.transfer.fail()
+				return n, err
+			}
+		}
+		written, err := w.ResponseWriter.Write(chunk)
+		n += written
+		w.transfer.add(written, time.Now())
+		if err != nil {
+			w.transfer.fail()
+			return n, err
+		}
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
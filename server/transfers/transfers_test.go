@@ -0,0 +1,183 @@
+package transfers_test
+
+import (
+	"boyl/pkg/testapp"
+	"boyl/server/transfers"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "boyl/server/migrations"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestLimitsFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected transfers.Limits
+		valid    bool
+	}{
+		{"unlimited", map[string]string{}, transfers.Limits{}, true},
+		{"all", map[string]string{
+			"MAX_DOWNLOADS_PER_USER": "2",
+			"MAX_DOWNLOAD_RATE":      "12.5",
+			"DOWNLOAD_QUOTA_DAILY":   "50",
+			"DOWNLOAD_QUOTA_MONTHLY": "0.5",
+		}, transfers.Limits{PerUser: 2, Rate: 12.5e6, Daily: 50e9, Monthly: 5e8}, true},
+		{"not a number", map[string]string{"MAX_DOWNLOADS_PER_USER": "two"}, transfers.Limits{}, false},
+		{"negative", map[string]string{"DOWNLOAD_QUOTA_DAILY": "-1"}, transfers.Limits{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"MAX_DOWNLOADS_PER_USER", "MAX_DOWNLOAD_RATE", "DOWNLOAD_QUOTA_DAILY", "DOWNLOAD_QUOTA_MONTHLY"} {
+				t.Setenv(name, test.env[name])
+			}
+
+			limits, err := transfers.LimitsFromEnv()
+			if !test.valid {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limits != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, limits)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	games := core.NewBaseCollection("games")
+	games.Fields.Add(&core.TextField{Name: "name"})
+	game := core.NewRecord(games)
+	game.Id = "g1"
+	game.Set("name", "Demo")
+	admin := core.NewRecord(core.NewAuthCollection(core.CollectionNameSuperusers))
+	admin.Id = "a1"
+
+	manager := transfers.NewManager(nil, nil, nil, transfers.Limits{PerUser: 1})
+	r := httptest.NewRequest(http.MethodGet, "/api/download?id=g1", nil)
+	first, err := manager.Start(admin, game, 2048, r, "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// admins aren't limited to PerUser downloads
+	if _, err := manager.Start(admin, game, 2048, r, "10.0.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	first.Writer(httptest.NewRecorder()).Write(make([]byte, 1024))
+
+	active := manager.Active()
+	if len(active) != 2 {
+		t.Fatalf("expected 2 active transfers, got %d", len(active))
+	}
+	status := active[0]
+	if status.User != "a1" || status.Game != "g1" || status.GameName != "Demo" || status.IP != "10.0.0.2" || status.Size != 2048 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	// both may have started at the same time, so their order isn't known
+	if bytes := active[0].Bytes + active[1].Bytes; bytes != 1024 {
+		t.Errorf("expected 1024 bytes, got %d", bytes)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	app := testapp.New(t)
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := core.NewRecord(users)
+	user.SetEmail("a@example.com")
+	user.SetPassword("password123")
+	if err := app.Save(user); err != nil {
+		t.Fatal(err)
+	}
+	games, err := app.FindCollectionByNameOrId("games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := core.NewRecord(games)
+	game.Set("name", "Demo")
+	if err := app.Save(game); err != nil {
+		t.Fatal(err)
+	}
+	usage, err := app.FindCollectionByNameOrId("usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfersCollection, err := app.FindCollectionByNameOrId("transfers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := transfers.NewManager(app, usage, transfersCollection, transfers.Limits{Daily: 1000})
+	r := httptest.NewRequest(http.MethodGet, "/api/download?id="+game.Id, nil)
+
+	// both start before either wrote anything, together they would exceed the quota
+	var started []*transfers.Transfer
+	for range 2 {
+		transfer, err := manager.Start(user, game, 800, r, "10.0.0.2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		started = append(started, transfer)
+	}
+
+	var wg sync.WaitGroup
+	written := make([]int, len(started))
+	for i, transfer := range started {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := transfer.Writer(httptest.NewRecorder())
+			for range 8 {
+				n, err := w.Write(make([]byte, 100))
+				written[i] += n
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, transfer := range started {
+		if err := transfer.Finish(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if total := written[0] + written[1]; total != 1000 {
+		t.Errorf("expected the transfers to stop at the quota of 1000 bytes, wrote %d", total)
+	}
+	daily, _, err := manager.Usage(user.Id, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if daily != 1000 {
+		t.Errorf("expected a usage of 1000 bytes, got %d", daily)
+	}
+	aborted, err := app.CountRecords(transfersCollection, dbx.HashExp{"status": "aborted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aborted != 1 {
+		t.Errorf("expected the transfer that hit the quota to be aborted, got %d aborted", aborted)
+	}
+
+	var limitErr *transfers.LimitError
+	if _, err := manager.Start(user, game, 800, r, "10.0.0.2"); !errors.As(err, &limitErr) {
+		t.Errorf("expected a LimitError once the quota is used up, got %v", err)
+	}
+}